@@ -0,0 +1,230 @@
+// Package anomaly flags implausible daily branch figures by comparing each day with the same
+// weekday in previous weeks, using a robust (median/MAD) z-score.
+package anomaly
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"rekap-backend/businessday"
+	"rekap-backend/model"
+	"rekap-backend/stats"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// baselineWeeks is how many previous same weekdays form the baseline
+	baselineWeeks = 8
+	// minBaselineDays is the fewest baseline days needed to judge a day
+	minBaselineDays = 4
+	// threshold is the robust z-score beyond which a figure is flagged
+	threshold = 3.5
+)
+
+// ErrNotify marks an error from the notifier: the anomalies were detected and saved, only
+// sending them failed
+var ErrNotify = errors.New("anomaly notification failed")
+
+// metric extracts one figure from a day's aggregates
+type metric struct {
+	Name  string
+	Label string
+	Value func(stats.Daily) float64
+}
+
+var metrics = []metric{
+	{"total_transactions", "transactions", func(d stats.Daily) float64 { return float64(d.TotalTransactions) }},
+	{"total_revenue", "revenue", func(d stats.Daily) float64 { return float64(d.TotalRevenue) }},
+	{"total_kg", "kg", func(d stats.Daily) float64 { return d.TotalKg }},
+	{"total_pc", "pieces", func(d stats.Daily) float64 { return float64(d.TotalPc) }},
+	{"total_paid", "paid transactions", func(d stats.Daily) float64 { return float64(d.TotalPaid) }},
+	{"total_discount", "discount total", func(d stats.Daily) float64 { return float64(d.TotalDiscount) }},
+	{"discounted_orders", "discounted orders", func(d stats.Daily) float64 { return float64(d.DiscountedOrders) }},
+}
+
+// Detect checks every branch's business dates from..to (inclusive) against their baselines.
+// A day without any transactions counts as zero, so a branch that stops recording is flagged.
+func Detect(db *gorm.DB, from, to time.Time) ([]model.Anomaly, error) {
+	historyStart := from.AddDate(0, 0, -7*baselineWeeks)
+	rows, err := stats.QueryDaily(db, businessday.WindowOf(historyStart, to), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// Index the days per branch and remember when each branch first recorded anything,
+	// so the weeks before a branch opened do not count as a baseline of zeros
+	byBranch := map[int]map[string]stats.Daily{}
+	firstSeen := map[int]string{}
+	for _, row := range rows {
+		if byBranch[row.BranchID] == nil {
+			byBranch[row.BranchID] = map[string]stats.Daily{}
+			firstSeen[row.BranchID] = row.Date // Rows are ordered by date
+		}
+		byBranch[row.BranchID][row.Date] = row
+	}
+
+	branchIDs := make([]int, 0, len(byBranch))
+	for id := range byBranch {
+		branchIDs = append(branchIDs, id)
+	}
+	sort.Ints(branchIDs)
+
+	var found []model.Anomaly
+	for _, branchID := range branchIDs {
+		days := byBranch[branchID]
+		for d := from; !d.After(to); d = d.AddDate(0, 0, 1) {
+			date := d.Format("2006-01-02")
+			if date < firstSeen[branchID] {
+				continue
+			}
+
+			var baselineDays []stats.Daily
+			for week := 1; week <= baselineWeeks; week++ {
+				past := d.AddDate(0, 0, -7*week).Format("2006-01-02")
+				if past >= firstSeen[branchID] {
+					baselineDays = append(baselineDays, days[past])
+				}
+			}
+			if len(baselineDays) < minBaselineDays {
+				continue
+			}
+
+			for _, m := range metrics {
+				baseline := make([]float64, len(baselineDays))
+				for i, day := range baselineDays {
+					baseline[i] = m.Value(day)
+				}
+
+				value := m.Value(days[date])
+				score, median, ok := robustScore(value, baseline)
+				if !ok || math.Abs(score) < threshold {
+					continue
+				}
+
+				found = append(found, model.Anomaly{
+					BranchID:     branchID,
+					BusinessDate: date,
+					Metric:       m.Name,
+					Value:        value,
+					Baseline:     median,
+					Score:        math.Round(score*100) / 100,
+					Explanation:  explain(m, d, value, median, score),
+					DetectedAt:   time.Now(),
+				})
+			}
+		}
+	}
+
+	return found, nil
+}
+
+// robustScore returns (value - median) / scale, where scale is the MAD scaled to match a
+// standard deviation. ok is false when the baseline is flat and the value matches it.
+func robustScore(value float64, baseline []float64) (score, median float64, ok bool) {
+	median = medianOf(baseline)
+
+	deviations := make([]float64, len(baseline))
+	var absSum float64
+	for i, v := range baseline {
+		deviations[i] = math.Abs(v - median)
+		absSum += deviations[i]
+	}
+
+	scale := 1.4826 * medianOf(deviations)
+	if scale == 0 {
+		// More than half the baseline is identical, fall back to the mean absolute deviation
+		scale = 1.2533 * absSum / float64(len(baseline))
+	}
+	if scale == 0 {
+		if value == median {
+			return 0, median, false
+		}
+		// A perfectly flat baseline: judge the change relative to its level
+		scale = math.Max(math.Abs(median)*0.1, 1)
+	}
+
+	return (value - median) / scale, median, true
+}
+
+func medianOf(values []float64) float64 {
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[mid-1] + sorted[mid]) / 2
+	}
+	return sorted[mid]
+}
+
+// explain describes an anomaly in one sentence
+func explain(m metric, day time.Time, value, median, score float64) string {
+	direction := "above"
+	if value < median {
+		direction = "below"
+	}
+
+	relative := ""
+	if median != 0 {
+		relative = fmt.Sprintf(" (%.0f%% of normal)", value/median*100)
+	}
+
+	return fmt.Sprintf("%s was %s%s, %s the usual %s of %s (robust z-score %.1f)",
+		capitalize(m.Label), formatNumber(value), relative, direction,
+		day.Weekday(), formatNumber(median), score)
+}
+
+func capitalize(s string) string {
+	if s == "" {
+		return s
+	}
+	return string(s[0]-'a'+'A') + s[1:]
+}
+
+func formatNumber(v float64) string {
+	if v == math.Trunc(v) {
+		return fmt.Sprintf("%.0f", v)
+	}
+	return fmt.Sprintf("%.2f", v)
+}
+
+// Save stores anomalies, keeping the first detection of each branch/date/metric.
+// It returns only the anomalies that were not known before.
+func Save(db *gorm.DB, anomalies []model.Anomaly) ([]model.Anomaly, error) {
+	var inserted []model.Anomaly
+	for _, a := range anomalies {
+		result := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&a)
+		if result.Error != nil {
+			return inserted, result.Error
+		}
+		if result.RowsAffected > 0 {
+			inserted = append(inserted, a)
+		}
+	}
+	return inserted, nil
+}
+
+// Run detects anomalies for the business dates from..to, stores them and sends the new ones to
+// the notifier (if any). A notification error wraps ErrNotify; any other error means detection or
+// saving failed, and inserted holds what was saved before that.
+func Run(db *gorm.DB, from, to time.Time, notifier Notifier) ([]model.Anomaly, error) {
+	found, err := Detect(db, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	inserted, err := Save(db, found)
+	if err != nil {
+		return inserted, err
+	}
+
+	if notifier != nil && len(inserted) > 0 {
+		if err := notifier.Notify(inserted); err != nil {
+			return inserted, fmt.Errorf("%w: %w", ErrNotify, err)
+		}
+	}
+	return inserted, nil
+}
@@ -0,0 +1,86 @@
+package anomaly
+
+import (
+	"math"
+	"testing"
+)
+
+func TestRobustScore(t *testing.T) {
+	tests := []struct {
+		name       string
+		value      float64
+		baseline   []float64
+		wantScore  float64
+		wantMedian float64
+		wantOK     bool
+	}{
+		{
+			name:      "spread baseline uses the MAD",
+			value:     20,
+			baseline:  []float64{10, 12, 11, 13, 9, 10, 12, 11},
+			wantScore: 9 / 1.4826, wantMedian: 11, wantOK: true,
+		},
+		{
+			name:      "below the baseline scores negative",
+			value:     5,
+			baseline:  []float64{10, 12, 11, 13, 9, 10, 12, 11},
+			wantScore: -6 / 1.4826, wantMedian: 11, wantOK: true,
+		},
+		{
+			name:      "MAD of zero falls back to the mean absolute deviation",
+			value:     15,
+			baseline:  []float64{10, 10, 10, 10, 10, 14, 6, 10},
+			wantScore: 5 / 1.2533, wantMedian: 10, wantOK: true,
+		},
+		{
+			name:      "flat baseline, same value",
+			value:     100,
+			baseline:  []float64{100, 100, 100, 100, 100, 100, 100, 100},
+			wantScore: 0, wantMedian: 100, wantOK: false,
+		},
+		{
+			name:      "flat baseline, changed value is judged against a tenth of the level",
+			value:     130,
+			baseline:  []float64{100, 100, 100, 100, 100, 100, 100, 100},
+			wantScore: 3, wantMedian: 100, wantOK: true,
+		},
+		{
+			name:      "flat zero baseline scales by at least one",
+			value:     2,
+			baseline:  []float64{0, 0, 0, 0, 0, 0, 0, 0},
+			wantScore: 2, wantMedian: 0, wantOK: true,
+		},
+		{
+			name:      "odd-length baseline",
+			value:     7,
+			baseline:  []float64{1, 3, 5},
+			wantScore: 2 / 1.4826, wantMedian: 3, wantOK: true,
+		},
+	}
+	for _, tt := range tests {
+		score, median, ok := robustScore(tt.value, tt.baseline)
+		if ok != tt.wantOK || median != tt.wantMedian || math.Abs(score-tt.wantScore) > 1e-9 {
+			t.Errorf("%s: robustScore = (%v, %v, %v), want (%v, %v, %v)",
+				tt.name, score, median, ok, tt.wantScore, tt.wantMedian, tt.wantOK)
+		}
+	}
+}
+
+func TestRobustScoreIgnoresOneOutlierInTheBaseline(t *testing.T) {
+	// A standard deviation would be inflated by the 500 and hide the spike
+	clean, _, _ := robustScore(20, []float64{10, 12, 11, 13, 9, 10, 12, 11})
+	spiked, _, _ := robustScore(20, []float64{10, 12, 11, 13, 9, 10, 12, 500})
+	if clean < threshold || spiked < threshold {
+		t.Errorf("scores %.2f and %.2f with an outlier in the baseline, want both flagged", clean, spiked)
+	}
+}
+
+func TestRobustScoreDoesNotModifyBaseline(t *testing.T) {
+	baseline := []float64{5, 1, 4, 2, 3}
+	robustScore(10, baseline)
+	for i, want := range []float64{5, 1, 4, 2, 3} {
+		if baseline[i] != want {
+			t.Fatalf("baseline changed to %v", baseline)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package anomaly
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"rekap-backend/config"
+	"rekap-backend/model"
+	"time"
+)
+
+// Notifier is told about newly detected anomalies
+type Notifier interface {
+	Notify(anomalies []model.Anomaly) error
+}
+
+// WebhookNotifier posts new anomalies as JSON ({"anomalies": [...]}) to a URL
+type WebhookNotifier struct {
+	URL    string
+	Client *http.Client
+}
+
+// NewWebhookNotifier returns a notifier for the given URL with a 10 second timeout
+func NewWebhookNotifier(url string) *WebhookNotifier {
+	return &WebhookNotifier{URL: url, Client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// Notify implements Notifier
+func (w *WebhookNotifier) Notify(anomalies []model.Anomaly) error {
+	if len(anomalies) == 0 {
+		return nil
+	}
+
+	body, err := json.Marshal(map[string]any{"anomalies": anomalies})
+	if err != nil {
+		return err
+	}
+
+	resp, err := w.Client.Post(w.URL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("anomaly webhook answered %s", resp.Status)
+	}
+	return nil
+}
+
+// ConfiguredNotifier returns a webhook notifier for ANOMALY_WEBHOOK_URL, or nil when it is unset
+func ConfiguredNotifier() Notifier {
+	url := config.App.AnomalyWebhookURL
+	if url == "" {
+		return nil
+	}
+	return NewWebhookNotifier(url)
+}
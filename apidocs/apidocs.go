@@ -0,0 +1,126 @@
+// Package apidocs serves the OpenAPI description of the API (openapi.yaml, kept by hand next to
+// this file) at /openapi.json, with Swagger UI at /docs.
+//
+// Every route registered on the router must have an operation in the spec and the other way
+// round; main_test.go checks this with CheckRoutes, so the spec cannot silently fall behind.
+package apidocs
+
+import (
+	_ "embed"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/goccy/go-yaml"
+)
+
+//go:embed openapi.yaml
+var specYAML []byte
+
+// operationMethods are the keys of an OpenAPI path item that describe an operation
+var operationMethods = map[string]bool{
+	"get": true, "put": true, "post": true, "delete": true,
+	"options": true, "head": true, "patch": true, "trace": true,
+}
+
+// pathParam matches gin's :name and *name path segments
+var pathParam = regexp.MustCompile(`[:*]([A-Za-z0-9_]+)`)
+
+// docsPage loads Swagger UI from a CDN and points it at the spec. Authorize takes an access
+// token from /api/auth/login; it is kept across reloads of the page.
+const docsPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>Rekap Backend API</title>
+<link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5/swagger-ui.css">
+</head>
+<body>
+<div id="swagger-ui"></div>
+<script src="https://unpkg.com/swagger-ui-dist@5/swagger-ui-bundle.js" crossorigin></script>
+<script>
+window.ui = SwaggerUIBundle({
+  url: "/openapi.json",
+  dom_id: "#swagger-ui",
+  persistAuthorization: true,
+});
+</script>
+</body>
+</html>`
+
+// Register serves the spec at GET /openapi.json and Swagger UI at GET /docs
+func Register(r gin.IRoutes) error {
+	spec, err := yaml.YAMLToJSON(specYAML)
+	if err != nil {
+		return fmt.Errorf("parse openapi.yaml: %w", err)
+	}
+
+	r.GET("/openapi.json", func(c *gin.Context) {
+		c.Data(http.StatusOK, "application/json; charset=utf-8", spec)
+	})
+	r.GET("/docs", func(c *gin.Context) {
+		c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(docsPage))
+	})
+	return nil
+}
+
+// CheckRoutes compares the registered routes with the operations in the spec. It reports every
+// route without an operation and every operation without a route.
+func CheckRoutes(routes gin.RoutesInfo) error {
+	documented, err := operations()
+	if err != nil {
+		return err
+	}
+
+	registered := make(map[string]bool, len(routes))
+	var errs []error
+	for _, route := range routes {
+		op := operationKey(route.Method, pathParam.ReplaceAllString(route.Path, "{$1}"))
+		registered[op] = true
+		if !documented[op] {
+			errs = append(errs, fmt.Errorf("route %s is missing from openapi.yaml", op))
+		}
+	}
+
+	// Sorted, so the report reads the same on every start
+	var stale []string
+	for op := range documented {
+		if !registered[op] {
+			stale = append(stale, op)
+		}
+	}
+	sort.Strings(stale)
+	for _, op := range stale {
+		errs = append(errs, fmt.Errorf("openapi.yaml documents %s, which is not a route", op))
+	}
+	return errors.Join(errs...)
+}
+
+// operations lists the operations in the spec as "METHOD /path"
+func operations() (map[string]bool, error) {
+	var spec struct {
+		Paths map[string]map[string]any `yaml:"paths"`
+	}
+	if err := yaml.Unmarshal(specYAML, &spec); err != nil {
+		return nil, fmt.Errorf("parse openapi.yaml: %w", err)
+	}
+
+	ops := make(map[string]bool)
+	for path, item := range spec.Paths {
+		for method := range item {
+			if operationMethods[method] {
+				ops[operationKey(method, path)] = true
+			}
+		}
+	}
+	return ops, nil
+}
+
+func operationKey(method, path string) string {
+	return strings.ToUpper(method) + " " + path
+}
@@ -0,0 +1,129 @@
+// Package apierror defines the body of every error response:
+//
+//	{"error": {"code": "validation_failed", "message": "Invalid request body",
+//	           "fields": {"email": "must be a valid email address"}, "request_id": "4f1c..."}}
+//
+// code is stable and meant for programs, message for people. fields is present when specific
+// request fields were rejected, request_id matches the X-Request-ID header and the logs.
+package apierror
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Error codes
+const (
+	CodeBadRequest       = "bad_request"        // Malformed request, e.g. a body that is not JSON
+	CodeValidationFailed = "validation_failed"  // One or more fields were rejected, see fields
+	CodeUnauthorized     = "unauthorized"       // Missing, invalid or expired credentials
+	CodeForbidden        = "forbidden"          // Authenticated, but not allowed to do this
+	CodeNotFound         = "not_found"          // The resource does not exist
+	CodeMethodNotAllowed = "method_not_allowed" // The route exists, but not for this method
+	CodeConflict         = "conflict"           // Clashes with the current state, e.g. a duplicate
+	CodeInternal         = "internal_error"     // Our fault, see the logs for the request ID
+)
+
+// FieldErrors maps a request field to the reason it was rejected
+type FieldErrors map[string]string
+
+// Error is the error object of a response
+type Error struct {
+	Code      string      `json:"code"`
+	Message   string      `json:"message"`
+	Fields    FieldErrors `json:"fields,omitempty"`
+	RequestID string      `json:"request_id,omitempty"`
+}
+
+// Response is the body of every error response
+type Response struct {
+	Error Error `json:"error"`
+}
+
+// New builds the response body for the current request
+func New(c *gin.Context, code, message string, fields FieldErrors) Response {
+	if len(fields) == 0 {
+		fields = nil
+	}
+	return Response{Error: Error{
+		Code:      code,
+		Message:   message,
+		Fields:    fields,
+		RequestID: c.GetString("request_id"),
+	}}
+}
+
+// Abort writes the error response and stops the handler chain
+func Abort(c *gin.Context, status int, code, message string) {
+	c.AbortWithStatusJSON(status, New(c, code, message, nil))
+}
+
+// BadRequest responds 400 for a request that could not be understood
+func BadRequest(c *gin.Context, message string) {
+	Abort(c, http.StatusBadRequest, CodeBadRequest, message)
+}
+
+// Invalid responds 400 with the rejected fields
+func Invalid(c *gin.Context, message string, fields FieldErrors) {
+	c.AbortWithStatusJSON(http.StatusBadRequest, New(c, CodeValidationFailed, message, fields))
+}
+
+// InvalidQuery responds 400 for rejected query parameters
+func InvalidQuery(c *gin.Context, fields FieldErrors) {
+	Invalid(c, "Invalid query parameters", fields)
+}
+
+// Unauthorized responds 401
+func Unauthorized(c *gin.Context, message string) {
+	Abort(c, http.StatusUnauthorized, CodeUnauthorized, message)
+}
+
+// Forbidden responds 403
+func Forbidden(c *gin.Context, message string) {
+	Abort(c, http.StatusForbidden, CodeForbidden, message)
+}
+
+// NotFound responds 404
+func NotFound(c *gin.Context, message string) {
+	Abort(c, http.StatusNotFound, CodeNotFound, message)
+}
+
+// Conflict responds 409
+func Conflict(c *gin.Context, message string) {
+	Abort(c, http.StatusConflict, CodeConflict, message)
+}
+
+// Internal responds 500 and attaches err to the request for the access log. message says what
+// failed; the cause stays in the logs.
+func Internal(c *gin.Context, err error, message string) {
+	c.Error(err)
+	Abort(c, http.StatusInternalServerError, CodeInternal, message)
+}
+
+// Fail responds to an error from the database or a service: 404 when a record was not found,
+// 409 for a unique violation, 400 for other constraint violations (a missing referenced record,
+// a null or a failed check), otherwise Internal with message
+func Fail(c *gin.Context, err error, message string) {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		NotFound(c, "Record not found")
+		return
+	}
+	if violation, ok := asConstraintViolation(err); ok {
+		switch violation.kind {
+		case uniqueViolation:
+			c.AbortWithStatusJSON(http.StatusConflict, New(c, CodeConflict,
+				"Conflicts with an existing record", violation.fields("is already taken")))
+		case foreignKeyViolation:
+			Invalid(c, "Refers to a record that does not exist", violation.fields("does not exist"))
+		case notNullViolation:
+			Invalid(c, "A required value is missing", violation.fields("is required"))
+		default:
+			Invalid(c, "Violates a data constraint", violation.fields("is not allowed"))
+		}
+		return
+	}
+	Internal(c, err, message)
+}
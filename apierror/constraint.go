@@ -0,0 +1,65 @@
+package apierror
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Postgres error codes of the constraint violations Fail maps to client errors
+const (
+	uniqueViolation     = "23505"
+	foreignKeyViolation = "23503"
+	checkViolation      = "23514"
+	notNullViolation    = "23502"
+)
+
+// detailColumns extracts the column list from details like
+// `Key (branch_id, business_date)=(1, 2026-01-16) already exists.`
+var detailColumns = regexp.MustCompile(`^Key \(([^)]+)\)=`)
+
+// constraintViolation is a Postgres constraint violation reduced to what a client may see
+type constraintViolation struct {
+	kind    string
+	columns []string
+}
+
+func asConstraintViolation(err error) (constraintViolation, bool) {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return constraintViolation{}, false
+	}
+	switch pgErr.Code {
+	case uniqueViolation, foreignKeyViolation, checkViolation, notNullViolation:
+	default:
+		return constraintViolation{}, false
+	}
+
+	// Only column names are passed on, the detail also holds the offending values
+	violation := constraintViolation{kind: pgErr.Code}
+	if pgErr.ColumnName != "" {
+		violation.columns = []string{pgErr.ColumnName}
+	} else if m := detailColumns.FindStringSubmatch(pgErr.Detail); m != nil {
+		for _, column := range strings.Split(m[1], ",") {
+			violation.columns = append(violation.columns, strings.TrimSpace(column))
+		}
+	}
+	return violation, true
+}
+
+// fields reports reason for every column involved
+func (v constraintViolation) fields(reason string) FieldErrors {
+	fields := FieldErrors{}
+	for _, column := range v.columns {
+		fields[column] = reason
+	}
+	return fields
+}
+
+// IsUniqueViolation reports whether err is a unique violation of the named constraint or index
+func IsUniqueViolation(err error, constraint string) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == uniqueViolation && pgErr.ConstraintName == constraint
+}
@@ -0,0 +1,159 @@
+// Package audit records an append-only trail of data-changing actions.
+//
+// Handlers describe what they changed with Set; Middleware writes the entry once the
+// handler has finished, adding the actor, request details and response status.
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"rekap-backend/config"
+	"rekap-backend/logging"
+	"rekap-backend/model"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+)
+
+// contextKey is where the pending entry is kept on the gin context
+const contextKey = "audit_entry"
+
+// Client-supplied text is cut to these lengths (in bytes) before it is stored
+const (
+	maxPathLength      = 512
+	maxUserAgentLength = 512
+)
+
+// Entry describes one auditable action
+type Entry struct {
+	Action   string // e.g. "transaction.toggle_payment", "auth.login_failed"
+	Entity   string // e.g. "transaction", "user"
+	EntityID string
+	Before   any            // State before the change, nil on create
+	After    any            // State after the change, nil on delete
+	UserID   *int           // Actor, defaults to user_id from the JWT context
+	Metadata map[string]any // Extra details, never secrets
+}
+
+// Change is the before/after value of a single field
+type Change struct {
+	From any `json:"from"`
+	To   any `json:"to"`
+}
+
+// Set records what the current request changed
+func Set(c *gin.Context, entry Entry) {
+	c.Set(contextKey, entry)
+}
+
+// Middleware writes an audit entry for every mutating request (POST, PUT, PATCH, DELETE) to a
+// known route, including rejected ones, so failed attempts leave a trail too. Anonymous requests
+// are only recorded when the handler described them as an auth.* event (such as a failed login):
+// the table is append-only, so unknown paths and requests without a valid token must not be able
+// to grow it.
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		switch c.Request.Method {
+		case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		default:
+			return
+		}
+		if c.FullPath() == "" {
+			return
+		}
+
+		entry := Entry{Action: c.Request.Method + " " + c.FullPath()}
+		v, described := c.Get(contextKey)
+		if described {
+			entry = v.(Entry)
+		}
+
+		if entry.UserID == nil {
+			if userID, ok := c.Get("user_id"); ok {
+				id := userID.(int)
+				entry.UserID = &id
+			} else if !described || !strings.HasPrefix(entry.Action, "auth.") {
+				return
+			}
+		}
+
+		record := model.AuditLog{
+			UserID:     entry.UserID,
+			Action:     entry.Action,
+			Entity:     entry.Entity,
+			EntityID:   entry.EntityID,
+			Method:     c.Request.Method,
+			Path:       truncate(c.Request.URL.Path, maxPathLength),
+			StatusCode: c.Writer.Status(),
+			IPAddress:  c.ClientIP(),
+			UserAgent:  truncate(c.Request.UserAgent(), maxUserAgentLength),
+		}
+
+		if changes := Diff(entry.Before, entry.After); len(changes) > 0 {
+			record.Changes, _ = json.Marshal(changes)
+		}
+		if len(entry.Metadata) > 0 {
+			record.Metadata, _ = json.Marshal(entry.Metadata)
+		}
+
+		// The change is committed by now, so record it even if the client has gone away
+		ctx := context.WithoutCancel(c.Request.Context())
+		if err := config.DB.WithContext(ctx).Create(&record).Error; err != nil {
+			logging.For(c).Error("Failed to write audit log", "action", record.Action, "error", err)
+		}
+	}
+}
+
+// truncate cuts s to at most limit bytes without splitting a UTF-8 sequence
+func truncate(s string, limit int) string {
+	if len(s) <= limit {
+		return s
+	}
+	for limit > 0 && !utf8.RuneStart(s[limit]) {
+		limit--
+	}
+	return s[:limit]
+}
+
+// Diff compares two values field by field through their JSON form and returns the changed fields.
+// A nil side (create or delete) reports every field of the other side.
+func Diff(before, after any) map[string]Change {
+	from := toFields(before)
+	to := toFields(after)
+
+	changes := map[string]Change{}
+	for field, value := range from {
+		if !reflect.DeepEqual(value, to[field]) {
+			changes[field] = Change{From: value, To: to[field]}
+		}
+	}
+	for field, value := range to {
+		if _, seen := from[field]; !seen {
+			changes[field] = Change{From: nil, To: value}
+		}
+	}
+	return changes
+}
+
+// toFields flattens a struct or map into its top-level JSON fields
+func toFields(v any) map[string]any {
+	if v == nil {
+		return nil
+	}
+
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+
+	var fields map[string]any
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return nil
+	}
+	return fields
+}
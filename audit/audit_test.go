@@ -0,0 +1,86 @@
+package audit
+
+import (
+	"reflect"
+	"testing"
+)
+
+type record struct {
+	ID     int      `json:"id"`
+	Name   string   `json:"name"`
+	Total  int64    `json:"total"`
+	Tags   []string `json:"tags"`
+	Secret string   `json:"-"`
+}
+
+func TestDiff(t *testing.T) {
+	base := record{ID: 1, Name: "Budi", Total: 25000, Tags: []string{"express"}, Secret: "a"}
+
+	tests := []struct {
+		name          string
+		before, after any
+		want          map[string]Change
+	}{
+		{
+			name:   "unchanged",
+			before: base,
+			after:  base,
+			want:   map[string]Change{},
+		},
+		{
+			name:   "unchanged apart from a field JSON leaves out",
+			before: base,
+			after:  record{ID: 1, Name: "Budi", Total: 25000, Tags: []string{"express"}, Secret: "b"},
+			want:   map[string]Change{},
+		},
+		{
+			name:   "changed",
+			before: base,
+			after:  record{ID: 1, Name: "Budi S.", Total: 30000, Tags: []string{"express", "antar"}},
+			want: map[string]Change{
+				"name":  {From: "Budi", To: "Budi S."},
+				"total": {From: float64(25000), To: float64(30000)},
+				"tags":  {From: []any{"express"}, To: []any{"express", "antar"}},
+			},
+		},
+		{
+			name:   "nil before",
+			before: nil,
+			after:  base,
+			want: map[string]Change{
+				"id":    {From: nil, To: float64(1)},
+				"name":  {From: nil, To: "Budi"},
+				"total": {From: nil, To: float64(25000)},
+				"tags":  {From: nil, To: []any{"express"}},
+			},
+		},
+		{
+			name:   "nil after",
+			before: base,
+			after:  nil,
+			want: map[string]Change{
+				"id":    {From: float64(1), To: nil},
+				"name":  {From: "Budi", To: nil},
+				"total": {From: float64(25000), To: nil},
+				"tags":  {From: []any{"express"}, To: nil},
+			},
+		},
+		{
+			name:   "both nil",
+			before: nil,
+			after:  nil,
+			want:   map[string]Change{},
+		},
+		{
+			name:   "maps",
+			before: map[string]any{"status": "belum lunas", "dp": 0},
+			after:  map[string]any{"status": "lunas", "dp": 0},
+			want:   map[string]Change{"status": {From: "belum lunas", To: "lunas"}},
+		},
+	}
+	for _, tt := range tests {
+		if got := Diff(tt.before, tt.after); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: Diff = %#v, want %#v", tt.name, got, tt.want)
+		}
+	}
+}
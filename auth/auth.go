@@ -2,51 +2,40 @@ package auth
 
 import (
 	"errors"
-	"os"
+	"rekap-backend/config"
 	"time"
 
 	jwt "github.com/golang-jwt/jwt/v5"
 )
 
-// getAccessSecret reads the JWT access secret from environment variable with a fallback default
+// getAccessSecret returns the JWT access secret. config.Load refuses to start without one
+// outside development.
 func getAccessSecret() []byte {
-	secret := os.Getenv("JWT_ACCESS_SECRET")
-	if secret == "" {
-		secret = "default-access-secret-change-in-production"
-	}
-	return []byte(secret)
+	return []byte(config.App.Auth.AccessSecret)
 }
 
-// getRefreshSecret reads the JWT refresh secret from environment variable with a fallback default
+// getRefreshSecret returns the JWT refresh secret
 func getRefreshSecret() []byte {
-	secret := os.Getenv("JWT_REFRESH_SECRET")
-	if secret == "" {
-		secret = "default-refresh-secret-change-in-production"
-	}
-	return []byte(secret)
+	return []byte(config.App.Auth.RefreshSecret)
 }
 
-// Token expiration durations
-const (
-	AccessTokenExpiry  = 1 * time.Hour       // Access token valid for 1 hour
-	RefreshTokenExpiry = 30 * 24 * time.Hour // Refresh token valid for 30 days
-)
-
 // Claims defines the custom JWT claims structure
 type Claims struct {
 	UserID int    `json:"user_id"`
 	Email  string `json:"email"`
+	Role   string `json:"role,omitempty"` // Only set on access tokens
 	jwt.RegisteredClaims
 }
 
 // GenerateAccessToken creates a short-lived access token
-func GenerateAccessToken(userID int, email string) (string, error) {
+func GenerateAccessToken(userID int, email, role string) (string, error) {
 	now := time.Now()
 	claims := Claims{
 		UserID: userID,
 		Email:  email,
+		Role:   role,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(now.Add(AccessTokenExpiry)),
+			ExpiresAt: jwt.NewNumericDate(now.Add(config.App.Auth.AccessTokenExpiry)),
 			IssuedAt:  jwt.NewNumericDate(now),
 			Issuer:    "rekap-laundry-api",
 		},
@@ -63,7 +52,7 @@ func GenerateRefreshToken(userID int, email string) (string, error) {
 		UserID: userID,
 		Email:  email,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(now.Add(RefreshTokenExpiry)),
+			ExpiresAt: jwt.NewNumericDate(now.Add(config.App.Auth.RefreshTokenExpiry)),
 			IssuedAt:  jwt.NewNumericDate(now),
 			Issuer:    "rekap-laundry-api",
 		},
@@ -104,13 +93,3 @@ func validateToken(tokenString string, secret []byte) (*Claims, error) {
 
 	return claims, nil
 }
-
-// RefreshAccessToken generates a new access token from a valid refresh token
-func RefreshAccessToken(refreshToken string) (string, error) {
-	claims, err := ValidateRefreshToken(refreshToken)
-	if err != nil {
-		return "", err
-	}
-
-	return GenerateAccessToken(claims.UserID, claims.Email)
-}
\ No newline at end of file
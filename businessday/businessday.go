@@ -0,0 +1,48 @@
+// Package businessday maps timestamps to business dates. A business date starts at the
+// configured cutoff hour in the business timezone, so late-night orders count towards the
+// previous day.
+package businessday
+
+import (
+	"fmt"
+	"rekap-backend/config"
+	"time"
+)
+
+// Window is the exact [start, end) tanggal_masuk range a query covered
+type Window struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// WindowOf returns the timestamp window covering the business dates from..to (inclusive).
+// With a cutoff of 02:00, business date 2026-01-16 runs from 2026-01-16 02:00 to 2026-01-17 02:00.
+func WindowOf(from, to time.Time) Window {
+	cutoff := time.Duration(config.BusinessDayCutoffHour) * time.Hour
+	return Window{
+		Start: from.Add(cutoff),
+		End:   to.AddDate(0, 0, 1).Add(cutoff),
+	}
+}
+
+// DateExpr returns the SQL expression that maps tanggal_masuk to its business date
+func DateExpr() string {
+	return fmt.Sprintf("DATE(tanggal_masuk - INTERVAL '%d hours')", config.BusinessDayCutoffHour)
+}
+
+// ParseDate parses a YYYY-MM-DD parameter as midnight in the business timezone
+func ParseDate(s string) (time.Time, error) {
+	return time.ParseInLocation("2006-01-02", s, config.BusinessLocation)
+}
+
+// Today returns the current business date, as YYYY-MM-DD. Before the cutoff hour that is
+// still yesterday's date.
+func Today() string {
+	return DateOf(time.Now()).Format("2006-01-02")
+}
+
+// DateOf returns the business date a timestamp belongs to, at midnight UTC
+func DateOf(t time.Time) time.Time {
+	shifted := t.In(config.BusinessLocation).Add(-time.Duration(config.BusinessDayCutoffHour) * time.Hour)
+	return time.Date(shifted.Year(), shifted.Month(), shifted.Day(), 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,216 @@
+// Package cache keeps rendered responses of the aggregate endpoints.
+//
+// Handlers describe which branches and business dates a response covers with SetScope;
+// Middleware stores successful responses under that scope and serves them with an ETag.
+// Writes call Invalidate for the branch and business date they touched, which drops exactly
+// the entries covering it.
+package cache
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"rekap-backend/businessday"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// contextKey is where the response scope is kept on the gin context
+const contextKey = "cache_scope"
+
+// DateRange is an inclusive range of business dates, YYYY-MM-DD
+type DateRange struct {
+	From string
+	To   string
+}
+
+// Scope is the data a cached response was computed from
+type Scope struct {
+	BranchIDs []int       // Nil means every branch
+	Ranges    []DateRange // Nil means every date
+}
+
+// Covers reports whether a change to branchID on date (YYYY-MM-DD) affects the scope
+func (s Scope) Covers(branchID int, date string) bool {
+	if s.BranchIDs != nil {
+		found := false
+		for _, id := range s.BranchIDs {
+			if id == branchID {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	if s.Ranges == nil {
+		return true
+	}
+	for _, r := range s.Ranges {
+		if date >= r.From && date <= r.To {
+			return true
+		}
+	}
+	return false
+}
+
+// Entry is a stored response
+type Entry struct {
+	Body        []byte
+	ContentType string
+	ETag        string
+	Scope       Scope
+	StoredAt    time.Time
+}
+
+// Backend stores entries. LRU is the in-process default; a shared store can implement the same
+// interface to serve several instances.
+type Backend interface {
+	Get(key string) (Entry, bool)
+	Set(key string, entry Entry)
+	// Invalidate drops every entry whose scope covers the branch and date
+	Invalidate(branchID int, date string)
+	// Purge drops every entry
+	Purge()
+}
+
+var (
+	backend    Backend
+	ttl        time.Duration
+	replicaLag time.Duration
+
+	// generation changes on every invalidation. A response is only stored if no invalidation
+	// happened while it was computed, otherwise it may predate the write.
+	generation atomic.Uint64
+)
+
+// Init enables the cache. Until it is called Middleware passes every request through.
+// With a read replica, lag is how far it may trail: invalidations are repeated after it, so a
+// response read from the replica before it caught up is not served until the TTL.
+func Init(b Backend, entryTTL, lag time.Duration) {
+	backend = b
+	ttl = entryTTL
+	replicaLag = lag
+}
+
+// SetScope records which branches and dates the current response covers. Responses without a
+// scope are not cached.
+func SetScope(c *gin.Context, scope Scope) {
+	c.Set(contextKey, scope)
+}
+
+// Invalidate drops the cached responses affected by a change to branchID on a business date.
+// Call it after the change is committed.
+func Invalidate(branchID int, date string) {
+	if backend == nil {
+		return
+	}
+	generation.Add(1)
+	backend.Invalidate(branchID, date)
+
+	if replicaLag > 0 {
+		time.AfterFunc(replicaLag, func() {
+			generation.Add(1)
+			backend.Invalidate(branchID, date)
+		})
+	}
+}
+
+// InvalidateAll drops every cached response, for changes spanning many branches and dates
+func InvalidateAll() {
+	if backend == nil {
+		return
+	}
+	generation.Add(1)
+	backend.Purge()
+
+	if replicaLag > 0 {
+		time.AfterFunc(replicaLag, func() {
+			generation.Add(1)
+			backend.Purge()
+		})
+	}
+}
+
+// Middleware serves GET requests from the cache and stores successful responses that called
+// SetScope. Entries are keyed by path, query, the caller's role and the current business date
+// (so "today" defaults roll over). Register it after the auth middleware.
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if backend == nil || c.Request.Method != http.MethodGet {
+			c.Next()
+			return
+		}
+
+		key := c.Request.URL.Path + "?" + c.Request.URL.Query().Encode() +
+			"#role=" + c.GetString("role") + "#day=" + businessday.Today()
+
+		if entry, ok := backend.Get(key); ok && time.Since(entry.StoredAt) < ttl {
+			c.Header("X-Cache", "HIT")
+			respond(c, entry)
+			c.Abort()
+			return
+		}
+
+		startGeneration := generation.Load()
+		writer := &bufferedWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+		c.Next()
+		c.Writer = writer.ResponseWriter
+
+		v, scoped := c.Get(contextKey)
+		if !scoped || writer.Status() != http.StatusOK {
+			c.Writer.Write(writer.body.Bytes())
+			return
+		}
+
+		sum := sha256.Sum256(writer.body.Bytes())
+		entry := Entry{
+			Body:        writer.body.Bytes(),
+			ContentType: c.Writer.Header().Get("Content-Type"),
+			ETag:        `"` + hex.EncodeToString(sum[:16]) + `"`,
+			Scope:       v.(Scope),
+			StoredAt:    time.Now(),
+		}
+		if generation.Load() == startGeneration {
+			backend.Set(key, entry)
+		}
+
+		c.Header("X-Cache", "MISS")
+		respond(c, entry)
+	}
+}
+
+// respond writes a cached entry, or 304 when the client already has it
+func respond(c *gin.Context, entry Entry) {
+	// Summaries are per user role and change with every transaction, so clients must revalidate
+	c.Header("Cache-Control", "private, no-cache")
+	c.Header("ETag", entry.ETag)
+
+	if match := c.GetHeader("If-None-Match"); match != "" && (match == entry.ETag || match == "*") {
+		c.Status(http.StatusNotModified)
+		c.Writer.WriteHeaderNow()
+		return
+	}
+
+	c.Data(http.StatusOK, entry.ContentType, entry.Body)
+}
+
+// bufferedWriter holds the handler's response body so headers can still be added after it ran
+type bufferedWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *bufferedWriter) Write(data []byte) (int, error) {
+	return w.body.Write(data)
+}
+
+func (w *bufferedWriter) WriteString(s string) (int, error) {
+	return w.body.WriteString(s)
+}
@@ -0,0 +1,132 @@
+package cache
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestScopeCovers(t *testing.T) {
+	january := DateRange{From: "2026-01-01", To: "2026-01-31"}
+	march := DateRange{From: "2026-03-01", To: "2026-03-31"}
+
+	tests := []struct {
+		name     string
+		scope    Scope
+		branchID int
+		date     string
+		want     bool
+	}{
+		{"everything", Scope{}, 7, "2026-05-05", true},
+		{"listed branch, any date", Scope{BranchIDs: []int{1, 3}}, 3, "2026-05-05", true},
+		{"other branch", Scope{BranchIDs: []int{1, 3}}, 2, "2026-05-05", false},
+		{"no branches", Scope{BranchIDs: []int{}}, 1, "2026-01-10", false},
+		{"every branch, in range", Scope{Ranges: []DateRange{january}}, 9, "2026-01-10", true},
+		{"first day of range", Scope{Ranges: []DateRange{january}}, 1, "2026-01-01", true},
+		{"last day of range", Scope{Ranges: []DateRange{january}}, 1, "2026-01-31", true},
+		{"day before range", Scope{Ranges: []DateRange{january}}, 1, "2025-12-31", false},
+		{"day after range", Scope{Ranges: []DateRange{january}}, 1, "2026-02-01", false},
+		{"between two ranges", Scope{Ranges: []DateRange{january, march}}, 1, "2026-02-15", false},
+		{"second of two ranges", Scope{Ranges: []DateRange{january, march}}, 1, "2026-03-15", true},
+		{"no ranges", Scope{Ranges: []DateRange{}}, 1, "2026-01-10", false},
+		{"branch and date", Scope{BranchIDs: []int{1}, Ranges: []DateRange{january}}, 1, "2026-01-10", true},
+		{"branch, other date", Scope{BranchIDs: []int{1}, Ranges: []DateRange{january}}, 1, "2026-03-10", false},
+		{"date, other branch", Scope{BranchIDs: []int{1}, Ranges: []DateRange{january}}, 2, "2026-01-10", false},
+	}
+	for _, tt := range tests {
+		if got := tt.scope.Covers(tt.branchID, tt.date); got != tt.want {
+			t.Errorf("%s: Covers(%d, %s) = %v, want %v", tt.name, tt.branchID, tt.date, got, tt.want)
+		}
+	}
+}
+
+// newCachedRouter serves /summary through Middleware with a handler scoped to branch 1 in
+// January, and counts how often the handler runs
+func newCachedRouter(t *testing.T, entryTTL time.Duration) (*gin.Engine, *int) {
+	gin.SetMode(gin.TestMode)
+	Init(NewLRU(10), entryTTL, 0)
+	t.Cleanup(func() { Init(nil, 0, 0) })
+
+	calls := 0
+	r := gin.New()
+	r.GET("/summary", Middleware(), func(c *gin.Context) {
+		calls++
+		SetScope(c, Scope{BranchIDs: []int{1}, Ranges: []DateRange{{"2026-01-01", "2026-01-31"}}})
+		c.JSON(http.StatusOK, gin.H{"calls": calls})
+	})
+	return r, &calls
+}
+
+// get requests /summary and returns the X-Cache header
+func get(r *gin.Engine) string {
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/summary", nil))
+	return w.Header().Get("X-Cache")
+}
+
+func TestMiddlewareServesUntilTTL(t *testing.T) {
+	r, calls := newCachedRouter(t, 50*time.Millisecond)
+
+	if got := get(r); got != "MISS" {
+		t.Errorf("first request: X-Cache %q, want MISS", got)
+	}
+	if got := get(r); got != "HIT" {
+		t.Errorf("second request: X-Cache %q, want HIT", got)
+	}
+	if *calls != 1 {
+		t.Errorf("handler ran %d times before the TTL, want 1", *calls)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	if got := get(r); got != "MISS" {
+		t.Errorf("after the TTL: X-Cache %q, want MISS", got)
+	}
+	if *calls != 2 {
+		t.Errorf("handler ran %d times after the TTL, want 2", *calls)
+	}
+}
+
+func TestMiddlewareInvalidatesByScope(t *testing.T) {
+	r, calls := newCachedRouter(t, time.Hour)
+	get(r)
+
+	// Writes outside the scope keep the entry
+	Invalidate(2, "2026-01-10")
+	Invalidate(1, "2026-02-01")
+	if got := get(r); got != "HIT" || *calls != 1 {
+		t.Errorf("after unrelated writes: X-Cache %q and %d calls, want HIT and 1", got, *calls)
+	}
+
+	// A write inside it drops the entry
+	Invalidate(1, "2026-01-10")
+	if got := get(r); got != "MISS" || *calls != 2 {
+		t.Errorf("after a covered write: X-Cache %q and %d calls, want MISS and 2", got, *calls)
+	}
+
+	InvalidateAll()
+	if got := get(r); got != "MISS" || *calls != 3 {
+		t.Errorf("after InvalidateAll: X-Cache %q and %d calls, want MISS and 3", got, *calls)
+	}
+}
+
+func TestMiddlewareRevalidatesWithETag(t *testing.T) {
+	r, _ := newCachedRouter(t, time.Hour)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/summary", nil))
+	etag := w.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("no ETag")
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/summary", nil)
+	req.Header.Set("If-None-Match", etag)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusNotModified || w.Body.Len() != 0 {
+		t.Errorf("status %d with %d bytes, want 304 without a body", w.Code, w.Body.Len())
+	}
+}
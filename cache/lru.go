@@ -0,0 +1,84 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+)
+
+// LRU is an in-process Backend holding at most a fixed number of entries, evicting the least
+// recently used one
+type LRU struct {
+	mu       sync.Mutex
+	capacity int
+	order    *list.List // Front is the most recently used
+	items    map[string]*list.Element
+}
+
+// lruItem is the value of an element in LRU.order
+type lruItem struct {
+	key   string
+	entry Entry
+}
+
+// NewLRU returns an empty LRU holding up to capacity entries
+func NewLRU(capacity int) *LRU {
+	return &LRU{
+		capacity: capacity,
+		order:    list.New(),
+		items:    map[string]*list.Element{},
+	}
+}
+
+// Get returns the entry for key and marks it as recently used
+func (l *LRU) Get(key string) (Entry, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	element, ok := l.items[key]
+	if !ok {
+		return Entry{}, false
+	}
+	l.order.MoveToFront(element)
+	return element.Value.(*lruItem).entry, true
+}
+
+// Set stores the entry for key, evicting the least recently used entry when full
+func (l *LRU) Set(key string, entry Entry) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if element, ok := l.items[key]; ok {
+		element.Value.(*lruItem).entry = entry
+		l.order.MoveToFront(element)
+		return
+	}
+
+	l.items[key] = l.order.PushFront(&lruItem{key: key, entry: entry})
+	for l.order.Len() > l.capacity {
+		oldest := l.order.Back()
+		l.order.Remove(oldest)
+		delete(l.items, oldest.Value.(*lruItem).key)
+	}
+}
+
+// Invalidate drops every entry whose scope covers the branch and date
+func (l *LRU) Invalidate(branchID int, date string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for key, element := range l.items {
+		if element.Value.(*lruItem).entry.Scope.Covers(branchID, date) {
+			l.order.Remove(element)
+			delete(l.items, key)
+		}
+	}
+}
+
+// Purge drops every entry
+func (l *LRU) Purge() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.order.Init()
+	l.items = map[string]*list.Element{}
+}
@@ -0,0 +1,100 @@
+package cache
+
+import (
+	"strconv"
+	"testing"
+)
+
+func entry(body string, scope Scope) Entry {
+	return Entry{Body: []byte(body), Scope: scope}
+}
+
+// keys returns the keys present in l out of candidates
+func keys(l *LRU, candidates ...string) []string {
+	var present []string
+	for _, key := range candidates {
+		l.mu.Lock()
+		_, ok := l.items[key]
+		l.mu.Unlock()
+		if ok {
+			present = append(present, key)
+		}
+	}
+	return present
+}
+
+func TestLRUEvictsLeastRecentlyUsed(t *testing.T) {
+	l := NewLRU(3)
+	l.Set("a", entry("a", Scope{}))
+	l.Set("b", entry("b", Scope{}))
+	l.Set("c", entry("c", Scope{}))
+
+	// Reading a makes b the oldest
+	if _, ok := l.Get("a"); !ok {
+		t.Fatal("a missing")
+	}
+	l.Set("d", entry("d", Scope{}))
+
+	if got := keys(l, "a", "b", "c", "d"); len(got) != 3 || got[0] != "a" || got[1] != "c" || got[2] != "d" {
+		t.Errorf("entries %v, want [a c d]", got)
+	}
+	if _, ok := l.Get("b"); ok {
+		t.Error("b was not evicted")
+	}
+}
+
+func TestLRUSetReplacesAndRefreshes(t *testing.T) {
+	l := NewLRU(2)
+	l.Set("a", entry("old", Scope{}))
+	l.Set("b", entry("b", Scope{}))
+	l.Set("a", entry("new", Scope{}))
+	l.Set("c", entry("c", Scope{}))
+
+	got, ok := l.Get("a")
+	if !ok || string(got.Body) != "new" {
+		t.Errorf("a = %q, %v, want the replaced entry", got.Body, ok)
+	}
+	if _, ok := l.Get("b"); ok {
+		t.Error("b was not evicted after a was replaced")
+	}
+	if l.order.Len() != 2 || len(l.items) != 2 {
+		t.Errorf("%d entries in the list and %d in the map, want 2", l.order.Len(), len(l.items))
+	}
+}
+
+func TestLRUInvalidate(t *testing.T) {
+	l := NewLRU(10)
+	l.Set("all", entry("", Scope{}))
+	l.Set("branch1-jan", entry("", Scope{BranchIDs: []int{1}, Ranges: []DateRange{{"2026-01-01", "2026-01-31"}}}))
+	l.Set("branch2-jan", entry("", Scope{BranchIDs: []int{2}, Ranges: []DateRange{{"2026-01-01", "2026-01-31"}}}))
+	l.Set("every-branch-feb", entry("", Scope{Ranges: []DateRange{{"2026-02-01", "2026-02-28"}}}))
+	l.Set("branch1-any-date", entry("", Scope{BranchIDs: []int{1}}))
+
+	l.Invalidate(1, "2026-01-16")
+
+	want := []string{"branch2-jan", "every-branch-feb"}
+	got := keys(l, "all", "branch1-jan", "branch2-jan", "every-branch-feb", "branch1-any-date")
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("entries %v after invalidating branch 1 on 2026-01-16, want %v", got, want)
+	}
+	if l.order.Len() != len(want) {
+		t.Errorf("%d entries in the list, want %d", l.order.Len(), len(want))
+	}
+}
+
+func TestLRUPurge(t *testing.T) {
+	l := NewLRU(10)
+	for i := 0; i < 5; i++ {
+		l.Set(strconv.Itoa(i), entry("", Scope{}))
+	}
+	l.Purge()
+	if l.order.Len() != 0 || len(l.items) != 0 {
+		t.Errorf("%d entries left after Purge", len(l.items))
+	}
+
+	// Still usable afterwards
+	l.Set("a", entry("a", Scope{}))
+	if _, ok := l.Get("a"); !ok {
+		t.Error("a missing after Purge and Set")
+	}
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"rekap-backend/anomaly"
+	"rekap-backend/businessday"
+	"rekap-backend/config"
+	"rekap-backend/dataquality"
+	"rekap-backend/logging"
+	"rekap-backend/stats"
+	"time"
+)
+
+// runCommand runs a one-off maintenance job instead of the HTTP server.
+// Usage: rekap-backend <command> [flags]
+func runCommand(args []string) {
+	switch args[0] {
+	case "data-quality":
+		runDataQuality(args[1:])
+	case "detect-anomalies":
+		runDetectAnomalies(args[1:])
+	case "rebuild-stats":
+		runRebuildStats(args[1:])
+	case "check-stats":
+		runCheckStats(args[1:])
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q, available: data-quality, detect-anomalies, rebuild-stats, check-stats\n", args[0])
+		os.Exit(2)
+	}
+}
+
+// managesRollup reports whether the command works on daily_branch_stats itself, so starting it
+// must not rebuild the rollup first
+func managesRollup(command string) bool {
+	return command == "rebuild-stats" || command == "check-stats"
+}
+
+// runDataQuality prints the data quality report, or with -fix the result of the safe fixes.
+// Fixes are a dry run unless -dry-run=false is given.
+func runDataQuality(args []string) {
+	flags := flag.NewFlagSet("data-quality", flag.ExitOnError)
+	fix := flags.Bool("fix", false, "apply the safe automatic fixes")
+	dryRun := flags.Bool("dry-run", true, "with -fix, only list what would change")
+	flags.Parse(args)
+
+	var result any
+	var err error
+	if *fix {
+		result, err = dataquality.Fix(config.DB, *dryRun)
+	} else {
+		result, err = dataquality.Scan(config.DB)
+	}
+	if err != nil {
+		logging.Fatal("Data quality job failed", err)
+	}
+
+	printJSON(result)
+}
+
+// runDetectAnomalies detects and stores anomalies for a range of business dates, yesterday by
+// default, and notifies ANOMALY_WEBHOOK_URL about new ones. Meant to run from cron after the day closes.
+func runDetectAnomalies(args []string) {
+	yesterday := businessday.DateOf(time.Now()).AddDate(0, 0, -1).Format("2006-01-02")
+
+	flags := flag.NewFlagSet("detect-anomalies", flag.ExitOnError)
+	start := flags.String("start", yesterday, "first business date (YYYY-MM-DD)")
+	end := flags.String("end", "", "last business date (YYYY-MM-DD), defaults to -start")
+	flags.Parse(args)
+
+	from, to := parseDateFlags(*start, *end)
+
+	inserted, err := anomaly.Run(config.DB, from, to, anomaly.ConfiguredNotifier())
+	if errors.Is(err, anomaly.ErrNotify) {
+		slog.Warn("Anomaly notification failed", "error", err)
+	} else if err != nil {
+		logging.Fatal("Anomaly detection failed", err)
+	}
+
+	printJSON(inserted)
+}
+
+// runRebuildStats recomputes daily_branch_stats from the transactions, for a range of business
+// dates with -start/-end, or entirely. Run it after importing transactions directly into the database.
+func runRebuildStats(args []string) {
+	flags := flag.NewFlagSet("rebuild-stats", flag.ExitOnError)
+	start := flags.String("start", "", "first business date (YYYY-MM-DD), omit to rebuild everything")
+	end := flags.String("end", "", "last business date (YYYY-MM-DD), defaults to -start")
+	flags.Parse(args)
+
+	var written int64
+	var err error
+	if *start == "" {
+		written, err = stats.RebuildAll(config.DB)
+	} else {
+		from, to := parseDateFlags(*start, *end)
+		written, err = stats.Rebuild(config.DB, from, to)
+	}
+	if err != nil {
+		logging.Fatal("Rebuilding daily stats failed", err)
+	}
+
+	printJSON(map[string]int64{"rows": written})
+}
+
+// runCheckStats compares daily_branch_stats with the transactions for a range of business dates,
+// the last 90 days by default, prints the differences and exits 1 if there are any
+func runCheckStats(args []string) {
+	today := businessday.DateOf(time.Now())
+
+	flags := flag.NewFlagSet("check-stats", flag.ExitOnError)
+	start := flags.String("start", today.AddDate(0, 0, -89).Format("2006-01-02"), "first business date (YYYY-MM-DD)")
+	end := flags.String("end", today.Format("2006-01-02"), "last business date (YYYY-MM-DD)")
+	flags.Parse(args)
+
+	from, to := parseDateFlags(*start, *end)
+	mismatches, err := stats.Check(config.DB, from, to)
+	if err != nil {
+		logging.Fatal("Checking daily stats failed", err)
+	}
+
+	printJSON(mismatches)
+	if len(mismatches) > 0 {
+		os.Exit(1)
+	}
+}
+
+// parseDateFlags parses -start/-end business dates, an empty end meaning the start date
+func parseDateFlags(start, end string) (from, to time.Time) {
+	if end == "" {
+		end = start
+	}
+	from, err := businessday.ParseDate(start)
+	if err != nil {
+		logging.Fatal("Invalid -start", err)
+	}
+	to, err = businessday.ParseDate(end)
+	if err != nil {
+		logging.Fatal("Invalid -end", err)
+	}
+	return from, to
+}
+
+// printJSON writes v to stdout as indented JSON
+func printJSON(v any) {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(v); err != nil {
+		logging.Fatal("Failed to write output", err)
+	}
+}
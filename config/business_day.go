@@ -0,0 +1,13 @@
+package config
+
+import "time"
+
+// BusinessDayCutoffHour is the hour (0-23) at which a new business day starts.
+// Orders recorded before this hour count towards the previous business day.
+// Set by Load from BUSINESS_DAY_CUTOFF_HOUR.
+var BusinessDayCutoffHour int
+
+// BusinessLocation is the timezone the branches operate in. Date parameters are read in it and
+// the database session uses it, so DATE(), EXTRACT() and TO_CHAR() see local wall-clock time.
+// Set by Load from BUSINESS_TIMEZONE.
+var BusinessLocation = time.UTC
@@ -0,0 +1,499 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+)
+
+// Default JWT secrets, only accepted in development
+const (
+	defaultAccessSecret  = "default-access-secret-change-in-production"
+	defaultRefreshSecret = "default-refresh-secret-change-in-production"
+)
+
+// minSecretLength is the shortest JWT secret accepted outside development
+const minSecretLength = 32
+
+// Config holds every setting of the service. See README.md for the variables and defaults.
+type Config struct {
+	Env  string // "production" or "development"
+	Port int
+
+	Server ServerConfig
+
+	Database DatabaseConfig
+	Auth     AuthConfig
+	CORS     CORSConfig
+
+	BusinessTimezone      string
+	BusinessDayCutoffHour int
+
+	SummaryCacheSize int
+	SummaryCacheTTL  time.Duration
+
+	AnomalyWebhookURL string
+
+	// LogLevel is the lowest slog level written: debug, info, warn or error
+	LogLevel slog.Level
+
+	// MetricsToken must be sent as a bearer token to read /metrics. The metrics include revenue
+	// per branch, so without a token /metrics is only served in development.
+	MetricsToken string
+
+	Tracing TracingConfig
+}
+
+// TracingConfig selects where OpenTelemetry spans go. The OTLP endpoint, headers and so on come
+// from the standard OTEL_EXPORTER_OTLP_* environment variables.
+type TracingConfig struct {
+	Exporter    string  // none, stdout or otlp
+	ServiceName string  // service.name on every span
+	SampleRatio float64 // Share of new traces recorded; requests with a sampled parent always are
+}
+
+// ServerConfig holds the HTTP server timeouts
+type ServerConfig struct {
+	ReadTimeout       time.Duration
+	ReadHeaderTimeout time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+	// After SIGTERM the server keeps serving for ShutdownDelay with /readyz failing, so the
+	// orchestrator stops routing to it, then gives in-flight requests ShutdownTimeout to finish.
+	// Keep the sum below the orchestrator's grace period.
+	ShutdownDelay   time.Duration
+	ShutdownTimeout time.Duration
+}
+
+// DatabaseConfig holds the Postgres connection and pool settings
+type DatabaseConfig struct {
+	Host     string
+	Port     int
+	User     string
+	Password string
+	Name     string
+
+	// TLS: sslmode plus optional certificate files, passed to the driver as in libpq
+	SSLMode     string
+	SSLRootCert string
+	SSLCert     string
+	SSLKey      string
+
+	// ReplicaDSN points read-only aggregate queries at a replica, empty to use the primary.
+	// ReplicaMaxLag is how far it may trail the primary; cached summaries are invalidated
+	// again after it.
+	ReplicaDSN    string
+	ReplicaMaxLag time.Duration
+
+	// Startup waits for Postgres: ConnectRetries attempts, backing off from ConnectBackoff
+	ConnectRetries int
+	ConnectBackoff time.Duration
+
+	// Statements slower than SlowQueryThreshold are logged as warnings
+	SlowQueryThreshold time.Duration
+
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+}
+
+// AuthConfig holds the JWT secrets and token lifetimes
+type AuthConfig struct {
+	AccessSecret       string
+	RefreshSecret      string
+	AccessTokenExpiry  time.Duration
+	RefreshTokenExpiry time.Duration
+}
+
+// CORSConfig lists which browser origins may call the API. No origins disables CORS.
+type CORSConfig struct {
+	AllowedOrigins   []string
+	AllowCredentials bool
+	MaxAge           time.Duration
+}
+
+// App is the loaded configuration, set by Load
+var App Config
+
+// IsDevelopment reports whether the service runs in development mode
+func (c Config) IsDevelopment() bool {
+	return c.Env == "development"
+}
+
+// Defaults returns the configuration used for every setting that is not given
+func Defaults() Config {
+	return Config{
+		Env:  "production",
+		Port: 8080,
+		Server: ServerConfig{
+			ReadTimeout:       15 * time.Second,
+			ReadHeaderTimeout: 5 * time.Second,
+			WriteTimeout:      60 * time.Second,
+			IdleTimeout:       120 * time.Second,
+			ShutdownDelay:     5 * time.Second,
+			ShutdownTimeout:   20 * time.Second,
+		},
+		Database: DatabaseConfig{
+			Port:            5432,
+			SSLMode:         "disable",
+			ReplicaMaxLag:   5 * time.Second,
+			ConnectRetries:  10,
+			ConnectBackoff:  time.Second,
+			MaxOpenConns:    25,
+			MaxIdleConns:    5,
+			ConnMaxLifetime: 30 * time.Minute,
+			ConnMaxIdleTime: 5 * time.Minute,
+
+			SlowQueryThreshold: 200 * time.Millisecond,
+		},
+		Auth: AuthConfig{
+			AccessTokenExpiry:  1 * time.Hour,       // Access token valid for 1 hour
+			RefreshTokenExpiry: 30 * 24 * time.Hour, // Refresh token valid for 30 days
+		},
+		CORS: CORSConfig{
+			MaxAge: 12 * time.Hour,
+		},
+		BusinessTimezone: "UTC",
+		SummaryCacheSize: 1000,
+		SummaryCacheTTL:  5 * time.Minute,
+		LogLevel:         slog.LevelInfo,
+		Tracing: TracingConfig{
+			Exporter:    "none",
+			ServiceName: "rekap-backend",
+			SampleRatio: 1,
+		},
+	}
+}
+
+// Load reads the configuration from, in increasing precedence: the defaults, a config file
+// (-config or CONFIG_FILE, else .env when present), environment variables and the global flags
+// in args. It validates the result, sets App and the business-day globals, and returns the
+// arguments left after the flags (the subcommand, if any).
+func Load(args []string) ([]string, error) {
+	flags := flag.NewFlagSet("rekap-backend", flag.ContinueOnError)
+	file := flags.String("config", os.Getenv("CONFIG_FILE"), "KEY=VALUE file with settings, overridden by the environment")
+	env := flags.String("env", "", "production or development (overrides APP_ENV)")
+	port := flags.Int("port", 0, "HTTP port (overrides PORT)")
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+
+	// The file is optional, containers pass everything through the environment
+	values := map[string]string{}
+	if *file != "" {
+		read, err := godotenv.Read(*file)
+		if err != nil {
+			return nil, fmt.Errorf("reading config file: %w", err)
+		}
+		values = read
+	} else if read, err := godotenv.Read(); err == nil {
+		values = read
+	}
+	lookup := func(key string) string {
+		if v, ok := os.LookupEnv(key); ok {
+			return v
+		}
+		return values[key]
+	}
+
+	cfg := Defaults()
+	var errs []error
+	r := reader{lookup: lookup, errs: &errs}
+
+	r.string("APP_ENV", &cfg.Env)
+	r.int("PORT", &cfg.Port)
+	r.duration("SERVER_READ_TIMEOUT", &cfg.Server.ReadTimeout)
+	r.duration("SERVER_READ_HEADER_TIMEOUT", &cfg.Server.ReadHeaderTimeout)
+	r.duration("SERVER_WRITE_TIMEOUT", &cfg.Server.WriteTimeout)
+	r.duration("SERVER_IDLE_TIMEOUT", &cfg.Server.IdleTimeout)
+	r.duration("SHUTDOWN_DELAY", &cfg.Server.ShutdownDelay)
+	r.duration("SHUTDOWN_TIMEOUT", &cfg.Server.ShutdownTimeout)
+
+	r.string("DB_HOST", &cfg.Database.Host)
+	r.int("DB_PORT", &cfg.Database.Port)
+	r.string("DB_USER", &cfg.Database.User)
+	r.string("DB_PASSWORD", &cfg.Database.Password)
+	r.string("DB_NAME", &cfg.Database.Name)
+	r.string("DB_SSLMODE", &cfg.Database.SSLMode)
+	r.string("DB_SSLROOTCERT", &cfg.Database.SSLRootCert)
+	r.string("DB_SSLCERT", &cfg.Database.SSLCert)
+	r.string("DB_SSLKEY", &cfg.Database.SSLKey)
+	r.string("DB_REPLICA_DSN", &cfg.Database.ReplicaDSN)
+	r.duration("DB_REPLICA_MAX_LAG", &cfg.Database.ReplicaMaxLag)
+	r.int("DB_CONNECT_RETRIES", &cfg.Database.ConnectRetries)
+	r.duration("DB_CONNECT_BACKOFF", &cfg.Database.ConnectBackoff)
+	r.duration("DB_SLOW_QUERY_THRESHOLD", &cfg.Database.SlowQueryThreshold)
+	r.int("DB_MAX_OPEN_CONNS", &cfg.Database.MaxOpenConns)
+	r.int("DB_MAX_IDLE_CONNS", &cfg.Database.MaxIdleConns)
+	r.duration("DB_CONN_MAX_LIFETIME", &cfg.Database.ConnMaxLifetime)
+	r.duration("DB_CONN_MAX_IDLE_TIME", &cfg.Database.ConnMaxIdleTime)
+
+	r.string("JWT_ACCESS_SECRET", &cfg.Auth.AccessSecret)
+	r.string("JWT_REFRESH_SECRET", &cfg.Auth.RefreshSecret)
+	r.duration("ACCESS_TOKEN_EXPIRY", &cfg.Auth.AccessTokenExpiry)
+	r.duration("REFRESH_TOKEN_EXPIRY", &cfg.Auth.RefreshTokenExpiry)
+
+	r.list("CORS_ALLOWED_ORIGINS", &cfg.CORS.AllowedOrigins)
+	r.bool("CORS_ALLOW_CREDENTIALS", &cfg.CORS.AllowCredentials)
+	r.duration("CORS_MAX_AGE", &cfg.CORS.MaxAge)
+
+	r.string("BUSINESS_TIMEZONE", &cfg.BusinessTimezone)
+	r.int("BUSINESS_DAY_CUTOFF_HOUR", &cfg.BusinessDayCutoffHour)
+
+	r.int("SUMMARY_CACHE_SIZE", &cfg.SummaryCacheSize)
+	r.duration("SUMMARY_CACHE_TTL", &cfg.SummaryCacheTTL)
+
+	r.string("ANOMALY_WEBHOOK_URL", &cfg.AnomalyWebhookURL)
+	r.string("METRICS_TOKEN", &cfg.MetricsToken)
+	r.level("LOG_LEVEL", &cfg.LogLevel)
+
+	r.string("TRACING_EXPORTER", &cfg.Tracing.Exporter)
+	r.string("OTEL_SERVICE_NAME", &cfg.Tracing.ServiceName)
+	r.float("TRACING_SAMPLE_RATIO", &cfg.Tracing.SampleRatio)
+
+	// Flags win over everything else
+	if *env != "" {
+		cfg.Env = *env
+	}
+	if *port != 0 {
+		cfg.Port = *port
+	}
+
+	// Development may run with the well-known secrets, production must not
+	if cfg.IsDevelopment() {
+		if cfg.Auth.AccessSecret == "" {
+			cfg.Auth.AccessSecret = defaultAccessSecret
+		}
+		if cfg.Auth.RefreshSecret == "" {
+			cfg.Auth.RefreshSecret = defaultRefreshSecret
+		}
+	}
+
+	errs = append(errs, cfg.Validate()...)
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("invalid configuration:\n%w", errors.Join(errs...))
+	}
+
+	location, _ := time.LoadLocation(cfg.BusinessTimezone) // Checked by Validate
+	BusinessLocation = location
+	BusinessDayCutoffHour = cfg.BusinessDayCutoffHour
+	App = cfg
+
+	return flags.Args(), nil
+}
+
+// Validate checks every setting and returns one error per problem
+func (c Config) Validate() []error {
+	var errs []error
+	fail := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	if c.Env != "production" && c.Env != "development" {
+		fail("APP_ENV must be production or development")
+	}
+	if c.Port < 1 || c.Port > 65535 {
+		fail("PORT must be between 1 and 65535")
+	}
+	server := c.Server
+	if server.ReadTimeout <= 0 || server.ReadHeaderTimeout <= 0 || server.WriteTimeout <= 0 ||
+		server.IdleTimeout <= 0 || server.ShutdownTimeout <= 0 {
+		fail("SERVER_*_TIMEOUT and SHUTDOWN_TIMEOUT must be positive durations")
+	}
+	if server.ShutdownDelay < 0 {
+		fail("SHUTDOWN_DELAY must not be negative")
+	}
+
+	db := c.Database
+	if db.Host == "" || db.User == "" || db.Name == "" {
+		fail("DB_HOST, DB_USER and DB_NAME are required")
+	}
+	if db.Port < 1 || db.Port > 65535 {
+		fail("DB_PORT must be between 1 and 65535")
+	}
+	switch db.SSLMode {
+	case "disable", "allow", "prefer", "require", "verify-ca", "verify-full":
+	default:
+		fail("DB_SSLMODE must be one of: disable, allow, prefer, require, verify-ca, verify-full")
+	}
+	if (db.SSLMode == "verify-ca" || db.SSLMode == "verify-full") && db.SSLRootCert == "" {
+		fail("DB_SSLROOTCERT is required with DB_SSLMODE=%s", db.SSLMode)
+	}
+	if (db.SSLCert == "") != (db.SSLKey == "") {
+		fail("DB_SSLCERT and DB_SSLKEY must be set together")
+	}
+	for _, file := range []struct{ name, path string }{
+		{"DB_SSLROOTCERT", db.SSLRootCert}, {"DB_SSLCERT", db.SSLCert}, {"DB_SSLKEY", db.SSLKey},
+	} {
+		if file.path == "" {
+			continue
+		}
+		if _, err := os.Stat(file.path); err != nil {
+			fail("%s: %v", file.name, err)
+		}
+	}
+	if db.SlowQueryThreshold < 0 {
+		fail("DB_SLOW_QUERY_THRESHOLD must not be negative")
+	}
+	if db.ReplicaMaxLag < 0 {
+		fail("DB_REPLICA_MAX_LAG must not be negative")
+	}
+	if db.ConnectRetries < 0 || db.ConnectBackoff <= 0 {
+		fail("DB_CONNECT_RETRIES must not be negative and DB_CONNECT_BACKOFF must be positive")
+	}
+	if db.MaxOpenConns < 0 || db.MaxIdleConns < 0 {
+		fail("DB_MAX_OPEN_CONNS and DB_MAX_IDLE_CONNS must not be negative")
+	}
+	if db.MaxOpenConns > 0 && db.MaxIdleConns > db.MaxOpenConns {
+		fail("DB_MAX_IDLE_CONNS must not exceed DB_MAX_OPEN_CONNS")
+	}
+	if db.ConnMaxLifetime < 0 || db.ConnMaxIdleTime < 0 {
+		fail("DB_CONN_MAX_LIFETIME and DB_CONN_MAX_IDLE_TIME must not be negative")
+	}
+
+	auth := c.Auth
+	if !c.IsDevelopment() {
+		secrets := []struct{ name, value string }{
+			{"JWT_ACCESS_SECRET", auth.AccessSecret},
+			{"JWT_REFRESH_SECRET", auth.RefreshSecret},
+		}
+		for _, secret := range secrets {
+			switch {
+			case secret.value == "" || secret.value == defaultAccessSecret || secret.value == defaultRefreshSecret:
+				fail("%s must be set to a secret of your own outside development", secret.name)
+			case len(secret.value) < minSecretLength:
+				fail("%s must be at least %d characters", secret.name, minSecretLength)
+			}
+		}
+		if auth.AccessSecret != "" && auth.AccessSecret == auth.RefreshSecret {
+			fail("JWT_ACCESS_SECRET and JWT_REFRESH_SECRET must differ")
+		}
+		if c.MetricsToken != "" && len(c.MetricsToken) < minSecretLength {
+			fail("METRICS_TOKEN must be at least %d characters", minSecretLength)
+		}
+	}
+	if auth.AccessTokenExpiry <= 0 || auth.RefreshTokenExpiry <= 0 {
+		fail("ACCESS_TOKEN_EXPIRY and REFRESH_TOKEN_EXPIRY must be positive")
+	} else if auth.RefreshTokenExpiry <= auth.AccessTokenExpiry {
+		fail("REFRESH_TOKEN_EXPIRY must be longer than ACCESS_TOKEN_EXPIRY")
+	}
+
+	for _, origin := range c.CORS.AllowedOrigins {
+		if origin == "*" && c.CORS.AllowCredentials {
+			fail("CORS_ALLOWED_ORIGINS must list origins explicitly when CORS_ALLOW_CREDENTIALS is true")
+		}
+	}
+
+	if _, err := time.LoadLocation(c.BusinessTimezone); err != nil {
+		fail("BUSINESS_TIMEZONE must be an IANA timezone name such as Asia/Jakarta")
+	}
+	if c.BusinessDayCutoffHour < 0 || c.BusinessDayCutoffHour > 23 {
+		fail("BUSINESS_DAY_CUTOFF_HOUR must be a whole hour between 0 and 23")
+	}
+
+	if c.SummaryCacheSize < 0 {
+		fail("SUMMARY_CACHE_SIZE must be a non-negative number of entries")
+	}
+	if c.SummaryCacheTTL <= 0 {
+		fail("SUMMARY_CACHE_TTL must be a positive duration such as 5m")
+	}
+
+	switch c.Tracing.Exporter {
+	case "none", "stdout", "otlp":
+	default:
+		fail("TRACING_EXPORTER must be one of: none, stdout, otlp")
+	}
+	if c.Tracing.ServiceName == "" {
+		fail("OTEL_SERVICE_NAME must not be empty")
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		fail("TRACING_SAMPLE_RATIO must be between 0 and 1")
+	}
+
+	return errs
+}
+
+// reader parses settings into typed fields, collecting errors instead of stopping at the first
+type reader struct {
+	lookup func(string) string
+	errs   *[]error
+}
+
+func (r reader) string(key string, dst *string) {
+	if v := r.lookup(key); v != "" {
+		*dst = v
+	}
+}
+
+func (r reader) int(key string, dst *int) {
+	if v := r.lookup(key); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			*r.errs = append(*r.errs, fmt.Errorf("%s must be a whole number", key))
+			return
+		}
+		*dst = n
+	}
+}
+
+func (r reader) bool(key string, dst *bool) {
+	if v := r.lookup(key); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			*r.errs = append(*r.errs, fmt.Errorf("%s must be true or false", key))
+			return
+		}
+		*dst = b
+	}
+}
+
+func (r reader) float(key string, dst *float64) {
+	if v := r.lookup(key); v != "" {
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			*r.errs = append(*r.errs, fmt.Errorf("%s must be a number", key))
+			return
+		}
+		*dst = f
+	}
+}
+
+func (r reader) duration(key string, dst *time.Duration) {
+	if v := r.lookup(key); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			*r.errs = append(*r.errs, fmt.Errorf("%s must be a duration such as 30s, 5m or 1h", key))
+			return
+		}
+		*dst = d
+	}
+}
+
+func (r reader) level(key string, dst *slog.Level) {
+	if v := r.lookup(key); v != "" {
+		if err := dst.UnmarshalText([]byte(v)); err != nil {
+			*r.errs = append(*r.errs, fmt.Errorf("%s must be one of: debug, info, warn, error", key))
+		}
+	}
+}
+
+// list reads a comma-separated value, skipping blanks
+func (r reader) list(key string, dst *[]string) {
+	if v := r.lookup(key); v != "" {
+		var items []string
+		for _, item := range strings.Split(v, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		*dst = items
+	}
+}
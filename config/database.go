@@ -1,43 +1,152 @@
 package config
 
 import (
+	"database/sql"
 	"fmt"
-	"log"
-	"os"
+	"log/slog"
+	"rekap-backend/logging"
+	"strings"
+	"time"
 
-	"github.com/joho/godotenv"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
 
+// DB is the primary database, used for every write and for reads that must see them
 var DB *gorm.DB
 
-func ConnectDatabase() {
-	err := godotenv.Load()
+// ReadDB serves heavy read-only queries (summaries, analytics, forecasts). It is the replica
+// when DB_REPLICA_DSN is set and DB otherwise, so it may trail the latest writes.
+var ReadDB *gorm.DB
+
+// maxConnectBackoff caps the wait between connection attempts
+const maxConnectBackoff = 30 * time.Second
+
+// ConnectDatabase opens the connection pools described by App.Database, waiting for Postgres
+// to come up with exponential backoff
+func ConnectDatabase() error {
+	db := App.Database
+
+	primary, err := open("primary", primaryDSN(db), db)
 	if err != nil {
-		log.Fatal("Error loading .env file")
+		return fmt.Errorf("connect to database: %w", err)
+	}
+	DB = primary
+	ReadDB = primary
+
+	if db.ReplicaDSN != "" {
+		replica, err := open("replica", replicaDSN(db.ReplicaDSN), db)
+		if err != nil {
+			return fmt.Errorf("connect to read replica: %w", err)
+		}
+		ReadDB = replica
 	}
 
-	host := os.Getenv("DB_HOST")
-	port := os.Getenv("DB_PORT")
-	user := os.Getenv("DB_USER")
-	password := os.Getenv("DB_PASSWORD")
-	dbname := os.Getenv("DB_NAME")
+	slog.Info("Database connected", "replica", db.ReplicaDSN != "")
+	return nil
+}
 
-	// Validate required environment variables
-	if host == "" || port == "" || user == "" || dbname == "" {
-		log.Fatal("Incomplete database config, check your .env file")
+// primaryDSN builds the primary's connection string.
+// The session timezone follows the business timezone (see BusinessLocation).
+func primaryDSN(db DatabaseConfig) string {
+	parts := []string{
+		"host=" + quoteDSN(db.Host),
+		fmt.Sprintf("port=%d", db.Port),
+		"user=" + quoteDSN(db.User),
+		"password=" + quoteDSN(db.Password),
+		"dbname=" + quoteDSN(db.Name),
+		"sslmode=" + db.SSLMode,
+		"TimeZone=" + BusinessLocation.String(),
 	}
+	if db.SSLRootCert != "" {
+		parts = append(parts, "sslrootcert="+quoteDSN(db.SSLRootCert))
+	}
+	if db.SSLCert != "" {
+		parts = append(parts, "sslcert="+quoteDSN(db.SSLCert), "sslkey="+quoteDSN(db.SSLKey))
+	}
+	return strings.Join(parts, " ")
+}
 
-	// Build connection string
-	dsn := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable", host, port, user, password, dbname)
+// replicaDSN adds the business timezone to the replica's connection string unless it sets one,
+// so business dates are computed the same way on both
+func replicaDSN(dsn string) string {
+	if strings.Contains(strings.ToLower(dsn), "timezone=") {
+		return dsn
+	}
+	if strings.Contains(dsn, "://") {
+		separator := "?"
+		if strings.Contains(dsn, "?") {
+			separator = "&"
+		}
+		return dsn + separator + "TimeZone=" + BusinessLocation.String()
+	}
+	return dsn + " TimeZone=" + BusinessLocation.String()
+}
 
-	// Connect to database
-	database, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
-	if err != nil {
-		log.Fatal("Failed to connect to database:", err)
+// quoteDSN quotes a key=value connection string value
+func quoteDSN(value string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(value) + "'"
+}
+
+// open connects to one database, retrying with exponential backoff, and applies the pool limits
+func open(name, dsn string, db DatabaseConfig) (*gorm.DB, error) {
+	backoff := db.ConnectBackoff
+	var err error
+	for attempt := 0; ; attempt++ {
+		var database *gorm.DB
+		database, err = gorm.Open(postgres.Open(dsn), &gorm.Config{
+			Logger: logging.NewGormLogger(db.SlowQueryThreshold),
+		})
+		if err == nil {
+			var sqlDB *sql.DB
+			if sqlDB, err = database.DB(); err != nil {
+				return nil, err
+			}
+			sqlDB.SetMaxOpenConns(db.MaxOpenConns)
+			sqlDB.SetMaxIdleConns(db.MaxIdleConns)
+			sqlDB.SetConnMaxLifetime(db.ConnMaxLifetime)
+			sqlDB.SetConnMaxIdleTime(db.ConnMaxIdleTime)
+			return database, nil
+		}
+
+		if attempt >= db.ConnectRetries {
+			return nil, err
+		}
+		slog.Warn("Database not reachable, retrying",
+			"database", name, "attempt", attempt+1, "attempts", db.ConnectRetries+1,
+			"retry_in", backoff.String(), "error", err)
+		time.Sleep(backoff)
+		backoff = min(backoff*2, maxConnectBackoff)
+	}
+}
+
+// PoolStats reports the connection pool usage of the primary, and of the replica when one is
+// configured
+func PoolStats() (map[string]sql.DBStats, error) {
+	result := map[string]sql.DBStats{}
+	pools := map[string]*gorm.DB{"primary": DB}
+	if ReadDB != DB {
+		pools["replica"] = ReadDB
+	}
+	for name, pool := range pools {
+		sqlDB, err := pool.DB()
+		if err != nil {
+			return nil, err
+		}
+		result[name] = sqlDB.Stats()
 	}
+	return result, nil
+}
 
-	DB = database
-	fmt.Println("Database connected successfully")
-}
\ No newline at end of file
+// CloseDatabase closes the connection pools, once the server no longer serves requests
+func CloseDatabase() {
+	pools := []*gorm.DB{DB}
+	if ReadDB != DB {
+		pools = append(pools, ReadDB)
+	}
+	for _, pool := range pools {
+		if sqlDB, err := pool.DB(); err == nil {
+			sqlDB.Close()
+		}
+	}
+}
@@ -0,0 +1,215 @@
+// Package dataquality scans the transactions table for rule violations, typically left behind
+// by POS imports, and repairs the cases that can be fixed without guessing.
+package dataquality
+
+import (
+	"rekap-backend/stats"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// maxIDsPerIssue caps how many offending IDs are listed per rule; Count is always exact
+const maxIDsPerIssue = 1000
+
+// rule is a single consistency check over live (not soft-deleted) transactions
+type rule struct {
+	Name        string
+	Description string
+	Where       string // SQL condition matching the offending rows
+}
+
+var rules = []rule{
+	{
+		Name:        "total_mismatch",
+		Description: "total does not equal subtotal + biaya_antar_jemput - diskon - diskon_poin",
+		Where:       "total <> subtotal + biaya_antar_jemput - diskon - diskon_poin",
+	},
+	{
+		Name:        "overpaid",
+		Description: "dp + pelunasan exceeds total",
+		Where:       "dp + pelunasan > total",
+	},
+	{
+		Name:        "lunas_with_balance",
+		Description: "status_pembayaran is 'lunas' but dp + pelunasan is less than total",
+		Where:       "status_pembayaran = 'lunas' AND dp + pelunasan < total",
+	},
+	{
+		Name:        "duplicate_no_transaksi",
+		Description: "the same no_transaksi appears more than once in a branch",
+		Where: `(branch_id, no_transaksi) IN (
+			SELECT branch_id, no_transaksi FROM transactions
+			WHERE deleted_at IS NULL
+			GROUP BY branch_id, no_transaksi
+			HAVING COUNT(*) > 1
+		)`,
+	},
+	{
+		Name:        "future_date",
+		Description: "tanggal_masuk is in the future",
+		Where:       "tanggal_masuk > NOW()",
+	},
+	{
+		Name:        "negative_kg",
+		Description: "jumlah_kg is negative",
+		Where:       "jumlah_kg < 0",
+	},
+	{
+		Name:        "blank_name",
+		Description: "nama_pelanggan is empty",
+		Where:       "TRIM(COALESCE(nama_pelanggan, '')) = ''",
+	},
+	{
+		Name:        "untrimmed_name",
+		Description: "nama_pelanggan has leading or trailing whitespace",
+		Where:       "nama_pelanggan <> TRIM(nama_pelanggan) AND TRIM(nama_pelanggan) <> ''",
+	},
+	{
+		Name:        "nonstandard_payment_status",
+		Description: "status_pembayaran differs from 'lunas' or 'belum lunas' only in case or whitespace",
+		Where:       "status_pembayaran NOT IN ('lunas', 'belum lunas') AND LOWER(TRIM(status_pembayaran)) IN ('lunas', 'belum lunas')",
+	},
+}
+
+// fix repairs the rows of one rule by rewriting a single column
+type fix struct {
+	Rule   string
+	Column string
+	Value  string // SQL expression for the repaired value
+}
+
+// fixes lists the safe repairs: they only normalize text, never money or dates
+var fixes = []fix{
+	{Rule: "untrimmed_name", Column: "nama_pelanggan", Value: "TRIM(nama_pelanggan)"},
+	{Rule: "nonstandard_payment_status", Column: "status_pembayaran", Value: "LOWER(TRIM(status_pembayaran))"},
+}
+
+// Issue is one violated rule with the offending transaction IDs
+type Issue struct {
+	Rule           string `json:"rule"`
+	Description    string `json:"description"`
+	Count          int64  `json:"count"`
+	TransactionIDs []int  `json:"transaction_ids"` // At most maxIDsPerIssue, lowest IDs first
+	AutoFixable    bool   `json:"auto_fixable"`
+}
+
+// Report is the result of a full scan
+type Report struct {
+	CheckedAt   time.Time `json:"checked_at"`
+	TotalIssues int64     `json:"total_issues"`
+	Issues      []Issue   `json:"issues"` // Only rules with at least one violation
+}
+
+// Change is a single value a fix rewrites (or would rewrite, in a dry run)
+type Change struct {
+	TransactionID int    `json:"transaction_id"`
+	Rule          string `json:"rule"`
+	Column        string `json:"column"`
+	From          string `json:"from"`
+	To            string `json:"to"`
+}
+
+// FixResult lists what a fix run changed
+type FixResult struct {
+	DryRun  bool     `json:"dry_run"`
+	Fixed   int64    `json:"fixed"`
+	Changes []Change `json:"changes"`
+}
+
+func isFixable(ruleName string) bool {
+	for _, f := range fixes {
+		if f.Rule == ruleName {
+			return true
+		}
+	}
+	return false
+}
+
+func ruleByName(name string) rule {
+	for _, r := range rules {
+		if r.Name == name {
+			return r
+		}
+	}
+	return rule{}
+}
+
+// violations returns the live rows matching a rule
+func violations(db *gorm.DB, r rule) *gorm.DB {
+	return db.Table("transactions").Where("deleted_at IS NULL").Where(r.Where)
+}
+
+// Scan checks every rule against the transactions table
+func Scan(db *gorm.DB) (Report, error) {
+	report := Report{CheckedAt: time.Now(), Issues: []Issue{}}
+
+	for _, r := range rules {
+		var count int64
+		if err := violations(db, r).Count(&count).Error; err != nil {
+			return report, err
+		}
+		if count == 0 {
+			continue
+		}
+
+		var ids []int
+		if err := violations(db, r).Order("id ASC").Limit(maxIDsPerIssue).Pluck("id", &ids).Error; err != nil {
+			return report, err
+		}
+
+		report.Issues = append(report.Issues, Issue{
+			Rule:           r.Name,
+			Description:    r.Description,
+			Count:          count,
+			TransactionIDs: ids,
+			AutoFixable:    isFixable(r.Name),
+		})
+		report.TotalIssues += count
+	}
+
+	return report, nil
+}
+
+// Fix applies the safe repairs. With dryRun it only reports what would change.
+func Fix(db *gorm.DB, dryRun bool) (FixResult, error) {
+	result := FixResult{DryRun: dryRun, Changes: []Change{}}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		for _, f := range fixes {
+			r := ruleByName(f.Rule)
+
+			var changes []Change
+			err := violations(tx, r).
+				Select("id AS transaction_id, ? AS rule, ? AS \"column\", "+f.Column+" AS \"from\", "+f.Value+" AS \"to\"", f.Rule, f.Column).
+				Order("id ASC").
+				Scan(&changes).Error
+			if err != nil {
+				return err
+			}
+			result.Changes = append(result.Changes, changes...)
+
+			if dryRun || len(changes) == 0 {
+				continue
+			}
+
+			update := violations(tx, r).Update(f.Column, gorm.Expr(f.Value))
+			if update.Error != nil {
+				return update.Error
+			}
+			result.Fixed += update.RowsAffected
+
+			// Fixes such as normalizing status_pembayaran change the paid counts
+			ids := make([]int, len(changes))
+			for i, change := range changes {
+				ids[i] = change.TransactionID
+			}
+			if err := stats.RefreshTransactions(tx, ids); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	return result, err
+}
@@ -0,0 +1,176 @@
+// Package forecast projects daily series with additive Holt-Winters (level, trend and a
+// repeating seasonal pattern, weekly for daily data). It is pure Go with no external services.
+package forecast
+
+import (
+	"errors"
+	"math"
+)
+
+// ErrNotEnoughHistory is returned when a series is shorter than two full seasons
+var ErrNotEnoughHistory = errors.New("not enough history, need at least two full seasons")
+
+// z95 is the normal quantile for a two-sided 95% prediction interval
+const z95 = 1.96
+
+// Candidate smoothing factors tried when fitting
+var (
+	alphas = []float64{0.05, 0.1, 0.2, 0.3, 0.5, 0.7}
+	betas  = []float64{0.01, 0.05, 0.1, 0.2}
+	gammas = []float64{0.05, 0.1, 0.2, 0.3, 0.5}
+)
+
+// Params are the Holt-Winters smoothing factors for level, trend and season
+type Params struct {
+	Alpha float64 `json:"alpha"`
+	Beta  float64 `json:"beta"`
+	Gamma float64 `json:"gamma"`
+}
+
+// Model is a Holt-Winters model fitted to a series
+type Model struct {
+	Params      Params
+	Period      int
+	level       float64
+	trend       float64
+	season      []float64
+	n           int     // Length of the fitted series
+	residualStd float64 // Standard deviation of the one-step-ahead errors
+}
+
+// Point is one forecast step with its 95% prediction interval
+type Point struct {
+	Value float64 `json:"value"`
+	Lower float64 `json:"lower"`
+	Upper float64 `json:"upper"`
+}
+
+// Accuracy reports how well the model predicted data it did not see
+type Accuracy struct {
+	Horizon int      `json:"horizon"`
+	MAE     float64  `json:"mae"`
+	RMSE    float64  `json:"rmse"`
+	MAPE    *float64 `json:"mape"` // Percent, over days with a non-zero actual; nil if there are none
+}
+
+// Fit picks the smoothing factors with the lowest one-step-ahead squared error
+func Fit(series []float64, period int) (Model, error) {
+	if period < 1 || len(series) < 2*period {
+		return Model{}, ErrNotEnoughHistory
+	}
+
+	var best Model
+	bestSSE := math.Inf(1)
+	for _, alpha := range alphas {
+		for _, beta := range betas {
+			for _, gamma := range gammas {
+				m, sse := run(series, period, Params{Alpha: alpha, Beta: beta, Gamma: gamma})
+				if sse < bestSSE {
+					best, bestSSE = m, sse
+				}
+			}
+		}
+	}
+	return best, nil
+}
+
+// run smooths the series with fixed factors and returns the final state and its SSE
+func run(series []float64, period int, p Params) (Model, float64) {
+	// Initial state from the first two seasons
+	first := mean(series[:period])
+	second := mean(series[period : 2*period])
+
+	level := first
+	trend := (second - first) / float64(period)
+	season := make([]float64, period)
+	for i := 0; i < period; i++ {
+		season[i] = series[i] - first
+	}
+
+	var sse float64
+	var steps int
+	for t := period; t < len(series); t++ {
+		s := season[t%period]
+		err := series[t] - (level + trend + s)
+		sse += err * err
+		steps++
+
+		newLevel := p.Alpha*(series[t]-s) + (1-p.Alpha)*(level+trend)
+		trend = p.Beta*(newLevel-level) + (1-p.Beta)*trend
+		season[t%period] = p.Gamma*(series[t]-newLevel) + (1-p.Gamma)*s
+		level = newLevel
+	}
+
+	return Model{
+		Params:      p,
+		Period:      period,
+		level:       level,
+		trend:       trend,
+		season:      season,
+		n:           len(series),
+		residualStd: math.Sqrt(sse / float64(steps)),
+	}, sse
+}
+
+// Forecast projects the next h steps. The interval widens with the square root of the
+// horizon; values and bounds are floored at zero since the series are counts and amounts.
+func (m Model) Forecast(h int) []Point {
+	points := make([]Point, h)
+	for k := 1; k <= h; k++ {
+		value := m.level + float64(k)*m.trend + m.season[(m.n+k-1)%m.Period]
+		spread := z95 * m.residualStd * math.Sqrt(float64(k))
+		points[k-1] = Point{
+			Value: math.Max(value, 0),
+			Lower: math.Max(value-spread, 0),
+			Upper: math.Max(value+spread, 0),
+		}
+	}
+	return points
+}
+
+// Backtest fits on all but the last horizon values, forecasts them and measures the error
+func Backtest(series []float64, period, horizon int) (Accuracy, error) {
+	if horizon < 1 || len(series)-horizon < 2*period {
+		return Accuracy{}, ErrNotEnoughHistory
+	}
+
+	train := series[:len(series)-horizon]
+	actual := series[len(series)-horizon:]
+
+	m, err := Fit(train, period)
+	if err != nil {
+		return Accuracy{}, err
+	}
+	predicted := m.Forecast(horizon)
+
+	var absSum, sqSum, pctSum float64
+	var pctCount int
+	for i, a := range actual {
+		diff := a - predicted[i].Value
+		absSum += math.Abs(diff)
+		sqSum += diff * diff
+		if a != 0 {
+			pctSum += math.Abs(diff / a)
+			pctCount++
+		}
+	}
+
+	acc := Accuracy{
+		Horizon: horizon,
+		MAE:     absSum / float64(horizon),
+		RMSE:    math.Sqrt(sqSum / float64(horizon)),
+	}
+	if pctCount > 0 {
+		mape := pctSum / float64(pctCount) * 100
+		acc.MAPE = &mape
+	}
+	return acc, nil
+}
+
+func mean(values []float64) float64 {
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
@@ -0,0 +1,150 @@
+package forecast
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+// weekly is a weekday pattern with quiet Mondays and busy weekends
+var weekly = []float64{-30, -10, 0, 5, 10, 40, 25}
+
+// seasonal returns n days of base + slope*t plus the weekly pattern, and noise of +-noise
+// alternating by day so the series stays deterministic
+func seasonal(n int, base, slope, noise float64) []float64 {
+	series := make([]float64, n)
+	for t := range series {
+		series[t] = base + slope*float64(t) + weekly[t%7]
+		if t%2 == 0 {
+			series[t] += noise
+		} else {
+			series[t] -= noise
+		}
+	}
+	return series
+}
+
+func TestFitNeedsTwoSeasons(t *testing.T) {
+	tests := []struct {
+		n, period int
+	}{
+		{0, 7},
+		{13, 7},
+		{20, 0},
+	}
+	for _, tt := range tests {
+		if _, err := Fit(seasonal(tt.n, 100, 0, 0), tt.period); !errors.Is(err, ErrNotEnoughHistory) {
+			t.Errorf("Fit(%d days, period %d): err = %v, want ErrNotEnoughHistory", tt.n, tt.period, err)
+		}
+	}
+	if _, err := Fit(seasonal(14, 100, 0, 0), 7); err != nil {
+		t.Errorf("Fit(14 days, period 7): %v", err)
+	}
+}
+
+func TestForecastRepeatsExactSeason(t *testing.T) {
+	series := seasonal(8*7, 200, 0, 0)
+	m, err := Fit(series, 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Every candidate fits a clean season, so the forecast repeats it with no interval
+	for k, p := range m.Forecast(14) {
+		want := 200 + weekly[(len(series)+k)%7]
+		if math.Abs(p.Value-want) > 1e-9 {
+			t.Errorf("day %d: forecast %v, want %v", k+1, p.Value, want)
+		}
+		if p.Upper-p.Lower > 1e-9 {
+			t.Errorf("day %d: interval [%v, %v] around %v, want none without residuals", k+1, p.Lower, p.Upper, p.Value)
+		}
+	}
+}
+
+func TestForecastFollowsTrendAndSeason(t *testing.T) {
+	series := seasonal(12*7, 500, 3, 4)
+	m, err := Fit(series, 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	points := m.Forecast(14)
+	for k, p := range points {
+		want := 500 + 3*float64(len(series)+k) + weekly[(len(series)+k)%7]
+		if math.Abs(p.Value-want) > 15 {
+			t.Errorf("day %d: forecast %.1f, want about %.1f", k+1, p.Value, want)
+		}
+		if !(p.Lower < p.Value && p.Value < p.Upper) {
+			t.Errorf("day %d: interval [%.1f, %.1f] does not contain %.1f", k+1, p.Lower, p.Upper, p.Value)
+		}
+	}
+
+	// The interval widens with the horizon
+	first, last := points[0], points[len(points)-1]
+	if last.Upper-last.Lower <= first.Upper-first.Lower {
+		t.Errorf("interval width %.2f at day 14, want more than %.2f at day 1",
+			last.Upper-last.Lower, first.Upper-first.Lower)
+	}
+}
+
+func TestForecastFloorsAtZero(t *testing.T) {
+	series := seasonal(6*7, 200, -5, 0)
+	m, err := Fit(series, 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for k, p := range m.Forecast(60) {
+		if p.Value < 0 || p.Lower < 0 || p.Upper < 0 {
+			t.Fatalf("day %d: %+v has a negative value", k+1, p)
+		}
+	}
+}
+
+func TestBacktest(t *testing.T) {
+	t.Run("exact season", func(t *testing.T) {
+		acc, err := Backtest(seasonal(10*7, 200, 0, 0), 7, 14)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if acc.Horizon != 14 || acc.MAE > 1e-9 || acc.RMSE > 1e-9 || acc.MAPE == nil || *acc.MAPE > 1e-9 {
+			t.Errorf("got %+v, want no error over 14 days", acc)
+		}
+	})
+
+	t.Run("noisy trend", func(t *testing.T) {
+		acc, err := Backtest(seasonal(12*7, 500, 3, 4), 7, 14)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if acc.MAE <= 0 || acc.MAE > 15 || acc.RMSE < acc.MAE || acc.RMSE > 20 {
+			t.Errorf("MAE %.2f, RMSE %.2f, want 0 < MAE <= RMSE and both small", acc.MAE, acc.RMSE)
+		}
+		if acc.MAPE == nil || *acc.MAPE > 3 {
+			t.Errorf("MAPE %v, want under 3%%", acc.MAPE)
+		}
+
+		// Same input, same result
+		again, _ := Backtest(seasonal(12*7, 500, 3, 4), 7, 14)
+		if again.MAE != acc.MAE || again.RMSE != acc.RMSE || *again.MAPE != *acc.MAPE {
+			t.Errorf("second run %+v, want %+v", again, acc)
+		}
+	})
+
+	t.Run("all zero actuals", func(t *testing.T) {
+		acc, err := Backtest(make([]float64, 5*7), 7, 7)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if acc.MAPE != nil || acc.MAE != 0 || acc.RMSE != 0 {
+			t.Errorf("got %+v, want zero error and no MAPE", acc)
+		}
+	})
+
+	t.Run("not enough history", func(t *testing.T) {
+		for _, horizon := range []int{0, 8} {
+			if _, err := Backtest(seasonal(3*7, 200, 0, 0), 7, horizon); !errors.Is(err, ErrNotEnoughHistory) {
+				t.Errorf("horizon %d: err = %v, want ErrNotEnoughHistory", horizon, err)
+			}
+		}
+	})
+}
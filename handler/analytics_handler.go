@@ -0,0 +1,299 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"rekap-backend/apierror"
+	"rekap-backend/businessday"
+	"rekap-backend/config"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Analytics list limits
+const (
+	defaultTopCustomers = 10
+	maxTopCustomers     = 100
+)
+
+// customerKeyExpr groups transactions by customer. There is no customer ID, so names are
+// compared case-insensitively with surrounding whitespace removed.
+const customerKeyExpr = "LOWER(TRIM(nama_pelanggan))"
+
+// CustomerStat holds one customer's activity. Spend is whole rupiah.
+type CustomerStat struct {
+	Name       string    `json:"name"`
+	Visits     int64     `json:"visits"`
+	Spend      int64     `json:"spend"`
+	TotalKg    float64   `json:"total_kg"`
+	FirstVisit time.Time `json:"first_visit"`
+	LastVisit  time.Time `json:"last_visit"`
+}
+
+// CustomerAnalytics is the customer report for a date range
+type CustomerAnalytics struct {
+	Customers            int64          `json:"customers"`
+	NewCustomers         int64          `json:"new_customers"`       // First visit ever falls in the range
+	ReturningCustomers   int64          `json:"returning_customers"` // Visited before the range too
+	Orders               int64          `json:"orders"`
+	AvgOrderValue        int64          `json:"avg_order_value"` // Whole rupiah, rounded
+	AvgKgPerOrder        float64        `json:"avg_kg_per_order"`
+	AvgDaysBetweenVisits *float64       `json:"avg_days_between_visits"` // Nil when nobody visited twice
+	TopBySpend           []CustomerStat `json:"top_by_spend"`
+	TopByVisits          []CustomerStat `json:"top_by_visits"`
+	Lapsed               []CustomerStat `json:"lapsed"` // Active in the previous period of equal length, absent in this one
+}
+
+// GetCustomerAnalytics returns top customers, new versus returning counts, visit frequency and
+// order averages, grouped by customer name.
+// Query params: start_date, end_date (YYYY-MM-DD). Optional: branch_id (repeatable), limit (top list size)
+func GetCustomerAnalytics(c *gin.Context) {
+	errs := apierror.FieldErrors{}
+	window := parseDateRangeParams(c, errs)
+	if window == nil && len(errs) == 0 {
+		errs["start_date"] = "is required"
+		errs["end_date"] = "is required"
+	}
+	branchIDs := parseBranchIDsParam(c, errs)
+
+	limit := defaultTopCustomers
+	if raw := c.Query("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 || n > maxTopCustomers {
+			errs["limit"] = fmt.Sprintf("must be an integer between 1 and %d", maxTopCustomers)
+		}
+		limit = n
+	}
+
+	if len(errs) > 0 {
+		apierror.InvalidQuery(c, errs)
+		return
+	}
+
+	// Shared filter: live rows with a customer name, optionally limited to some branches
+	scope := "deleted_at IS NULL AND TRIM(nama_pelanggan) <> ''"
+	scopeArgs := []any{}
+	if len(branchIDs) > 0 {
+		scope += " AND branch_id IN ?"
+		scopeArgs = append(scopeArgs, branchIDs)
+	}
+	inWindow := func(start, end time.Time) []any {
+		return append(append([]any{}, scopeArgs...), start, end)
+	}
+
+	var result CustomerAnalytics
+	db := config.ReadDB.WithContext(c)
+
+	// GORM resets a struct on every Scan, so each query gets its own target
+	var totals struct {
+		Customers     int64
+		Orders        int64
+		AvgOrderValue int64
+		AvgKgPerOrder float64
+	}
+	var split struct {
+		NewCustomers       int64
+		ReturningCustomers int64
+	}
+	var frequency struct {
+		AvgDaysBetweenVisits *float64
+	}
+
+	// Order totals and averages
+	err := db.Raw(`
+		SELECT
+			COUNT(DISTINCT `+customerKeyExpr+`) AS customers,
+			COUNT(*) AS orders,
+			COALESCE(ROUND(AVG(total)), 0)::BIGINT AS avg_order_value,
+			COALESCE(AVG(jumlah_kg), 0) AS avg_kg_per_order
+		FROM transactions
+		WHERE `+scope+` AND tanggal_masuk >= ? AND tanggal_masuk < ?
+	`, inWindow(window.Start, window.End)...).Scan(&totals).Error
+	if err != nil {
+		apierror.Internal(c, err, "Failed to build customer analytics")
+		return
+	}
+
+	// New versus returning: compare each active customer's first visit ever with the range start
+	err = db.Raw(`
+		SELECT
+			COUNT(CASE WHEN first_visit >= ? THEN 1 END) AS new_customers,
+			COUNT(CASE WHEN first_visit < ? THEN 1 END) AS returning_customers
+		FROM (
+			SELECT MIN(tanggal_masuk) AS first_visit
+			FROM transactions
+			WHERE `+scope+` AND tanggal_masuk < ?
+			GROUP BY `+customerKeyExpr+`
+			HAVING MAX(tanggal_masuk) >= ?
+		) active
+	`, append(append([]any{window.Start, window.Start}, scopeArgs...), window.End, window.Start)...).Scan(&split).Error
+	if err != nil {
+		apierror.Internal(c, err, "Failed to build customer analytics")
+		return
+	}
+
+	// Average gap between consecutive visits of the same customer within the range
+	err = db.Raw(`
+		SELECT AVG(gap_days) AS avg_days_between_visits
+		FROM (
+			SELECT EXTRACT(EPOCH FROM tanggal_masuk - LAG(tanggal_masuk) OVER (
+				PARTITION BY `+customerKeyExpr+` ORDER BY tanggal_masuk
+			)) / 86400 AS gap_days
+			FROM transactions
+			WHERE `+scope+` AND tanggal_masuk >= ? AND tanggal_masuk < ?
+		) gaps
+		WHERE gap_days IS NOT NULL
+	`, inWindow(window.Start, window.End)...).Scan(&frequency).Error
+	if err != nil {
+		apierror.Internal(c, err, "Failed to build customer analytics")
+		return
+	}
+
+	result.Customers = totals.Customers
+	result.Orders = totals.Orders
+	result.AvgOrderValue = totals.AvgOrderValue
+	result.AvgKgPerOrder = totals.AvgKgPerOrder
+	result.NewCustomers = split.NewCustomers
+	result.ReturningCustomers = split.ReturningCustomers
+	result.AvgDaysBetweenVisits = frequency.AvgDaysBetweenVisits
+
+	customerStats := func(start, end time.Time, order string, exclude *businessday.Window) ([]CustomerStat, error) {
+		sql := `
+			SELECT
+				MAX(TRIM(nama_pelanggan)) AS name,
+				COUNT(*) AS visits,
+				COALESCE(SUM(total), 0)::BIGINT AS spend,
+				COALESCE(SUM(jumlah_kg), 0) AS total_kg,
+				MIN(tanggal_masuk) AS first_visit,
+				MAX(tanggal_masuk) AS last_visit
+			FROM transactions
+			WHERE ` + scope + ` AND tanggal_masuk >= ? AND tanggal_masuk < ?`
+		args := inWindow(start, end)
+
+		if exclude != nil {
+			sql += `
+				AND ` + customerKeyExpr + ` NOT IN (
+					SELECT ` + customerKeyExpr + ` FROM transactions
+					WHERE ` + scope + ` AND tanggal_masuk >= ? AND tanggal_masuk < ?
+				)`
+			args = append(args, inWindow(exclude.Start, exclude.End)...)
+		}
+
+		sql += `
+			GROUP BY ` + customerKeyExpr + `
+			ORDER BY ` + order + `
+			LIMIT ?`
+		args = append(args, limit)
+
+		stats := []CustomerStat{}
+		err := db.Raw(sql, args...).Scan(&stats).Error
+		return stats, err
+	}
+
+	if result.TopBySpend, err = customerStats(window.Start, window.End, "spend DESC, visits DESC", nil); err != nil {
+		apierror.Internal(c, err, "Failed to build customer analytics")
+		return
+	}
+	if result.TopByVisits, err = customerStats(window.Start, window.End, "visits DESC, spend DESC", nil); err != nil {
+		apierror.Internal(c, err, "Failed to build customer analytics")
+		return
+	}
+
+	// Lapsed: the best customers of the previous period who did not come back in this one
+	previousStart := window.Start.Add(-window.End.Sub(window.Start))
+	if result.Lapsed, err = customerStats(previousStart, window.Start, "spend DESC, visits DESC", window); err != nil {
+		apierror.Internal(c, err, "Failed to build customer analytics")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":   result,
+		"window": window,
+	})
+}
+
+// defaultPeakSlots is how many of the busiest slots the heatmap highlights
+const defaultPeakSlots = 5
+
+// HeatmapCell holds the demand of one weekday × hour slot. Revenue is whole rupiah.
+type HeatmapCell struct {
+	Weekday      int     `json:"weekday"` // ISO weekday of the business date, 1 = Monday ... 7 = Sunday
+	Hour         int     `json:"hour"`    // Hour of tanggal_masuk in the business timezone, 0-23
+	Transactions int64   `json:"transactions"`
+	TotalKg      float64 `json:"total_kg"`
+	TotalRevenue int64   `json:"total_revenue"`
+}
+
+// GetDemandHeatmap aggregates transaction count, kg and revenue by weekday × hour in one query,
+// returning the full 7×24 grid and the busiest slots. After-midnight orders count towards the
+// weekday of their business date, so a late Friday shift stays on Friday.
+// Query params: start_date, end_date (YYYY-MM-DD). Optional: branch_id (repeatable)
+func GetDemandHeatmap(c *gin.Context) {
+	errs := apierror.FieldErrors{}
+	window := parseDateRangeParams(c, errs)
+	if window == nil && len(errs) == 0 {
+		errs["start_date"] = "is required"
+		errs["end_date"] = "is required"
+	}
+	branchIDs := parseBranchIDsParam(c, errs)
+
+	if len(errs) > 0 {
+		apierror.InvalidQuery(c, errs)
+		return
+	}
+
+	query := config.ReadDB.WithContext(c).Table("transactions").
+		Where("deleted_at IS NULL").
+		Where("tanggal_masuk >= ? AND tanggal_masuk < ?", window.Start, window.End)
+	if len(branchIDs) > 0 {
+		query = query.Where("branch_id IN ?", branchIDs)
+	}
+
+	var rows []HeatmapCell
+	err := query.Select(`
+		EXTRACT(ISODOW FROM ` + businessday.DateExpr() + `)::INT as weekday,
+		EXTRACT(HOUR FROM tanggal_masuk)::INT as hour,
+		COUNT(*) as transactions,
+		COALESCE(SUM(jumlah_kg), 0) as total_kg,
+		COALESCE(SUM(total), 0)::BIGINT as total_revenue
+	`).Group("1, 2").Scan(&rows).Error
+	if err != nil {
+		apierror.Internal(c, err, "Failed to build demand heatmap")
+		return
+	}
+
+	// Fill the whole grid so empty slots show up as zeros
+	grid := make([]HeatmapCell, 0, 7*24)
+	for weekday := 1; weekday <= 7; weekday++ {
+		for hour := 0; hour < 24; hour++ {
+			grid = append(grid, HeatmapCell{Weekday: weekday, Hour: hour})
+		}
+	}
+	for _, row := range rows {
+		if row.Weekday >= 1 && row.Weekday <= 7 && row.Hour >= 0 && row.Hour < 24 {
+			grid[(row.Weekday-1)*24+row.Hour] = row
+		}
+	}
+
+	peaks := make([]HeatmapCell, 0, len(rows))
+	peaks = append(peaks, rows...)
+	sort.Slice(peaks, func(i, j int) bool {
+		if peaks[i].Transactions != peaks[j].Transactions {
+			return peaks[i].Transactions > peaks[j].Transactions
+		}
+		return peaks[i].TotalRevenue > peaks[j].TotalRevenue
+	})
+	if len(peaks) > defaultPeakSlots {
+		peaks = peaks[:defaultPeakSlots]
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":     grid,
+		"peaks":    peaks,
+		"timezone": config.BusinessLocation.String(),
+		"window":   window,
+	})
+}
@@ -0,0 +1,113 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"rekap-backend/anomaly"
+	"rekap-backend/apierror"
+	"rekap-backend/audit"
+	"rekap-backend/businessday"
+	"rekap-backend/config"
+	"rekap-backend/logging"
+	"rekap-backend/model"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxDetectionDays caps one detection run; every day compares each branch against eight weeks
+const maxDetectionDays = 366
+
+// GetAnomalies returns flagged daily branch figures, latest first.
+// Optional query params: start_date, end_date (YYYY-MM-DD, business dates), branch_id (repeatable),
+// metric, min_score (absolute robust z-score)
+func GetAnomalies(c *gin.Context) {
+	errs := apierror.FieldErrors{}
+	startDate, endDate, hasRange := parseDates(c, errs)
+	branchIDs := parseBranchIDsParam(c, errs)
+
+	var minScore float64
+	if raw := c.Query("min_score"); raw != "" {
+		score, err := strconv.ParseFloat(raw, 64)
+		if err != nil || score < 0 {
+			errs["min_score"] = "must be a non-negative number"
+		}
+		minScore = score
+	}
+
+	if len(errs) > 0 {
+		apierror.InvalidQuery(c, errs)
+		return
+	}
+
+	query := config.DB.WithContext(c).Model(&model.Anomaly{}).Select(`
+		id, branch_id, TO_CHAR(business_date, 'YYYY-MM-DD') as business_date, metric,
+		value, baseline, score, explanation, detected_at
+	`)
+	if hasRange {
+		query = query.Where("business_date BETWEEN ? AND ?", startDate.Format("2006-01-02"), endDate.Format("2006-01-02"))
+	}
+	if len(branchIDs) > 0 {
+		query = query.Where("branch_id IN ?", branchIDs)
+	}
+	if m := c.Query("metric"); m != "" {
+		query = query.Where("metric = ?", m)
+	}
+	if minScore > 0 {
+		query = query.Where("ABS(score) >= ?", minScore)
+	}
+
+	var anomalies []model.Anomaly
+	if err := query.Order("business_date DESC, ABS(score) DESC").Limit(maxPageSize).Find(&anomalies).Error; err != nil {
+		apierror.Internal(c, err, "Failed to fetch anomalies")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": anomalies})
+}
+
+// DetectAnomalies runs anomaly detection on demand. Owner only.
+// Optional query params: start_date, end_date (YYYY-MM-DD, business dates), default yesterday.
+// A run covers at most maxDetectionDays.
+func DetectAnomalies(c *gin.Context) {
+	errs := apierror.FieldErrors{}
+	startDate, endDate, ok := parseDates(c, errs)
+	if ok && daysBetween(startDate, endDate) > maxDetectionDays {
+		errs["end_date"] = fmt.Sprintf("must be at most %d days from start_date", maxDetectionDays-1)
+	}
+	if len(errs) > 0 {
+		apierror.InvalidQuery(c, errs)
+		return
+	}
+	if !ok {
+		startDate, endDate = yesterday(), yesterday()
+	}
+
+	inserted, err := anomaly.Run(config.DB.WithContext(c), startDate, endDate, anomaly.ConfiguredNotifier())
+	if errors.Is(err, anomaly.ErrNotify) {
+		// Detected and saved, only the notification failed
+		logging.For(c).Warn("Anomaly notification failed", "error", err)
+	} else if err != nil {
+		apierror.Internal(c, err, "Failed to detect anomalies")
+		return
+	}
+
+	audit.Set(c, audit.Entry{
+		Action:   "anomaly.detect",
+		Entity:   "anomaly",
+		Metadata: map[string]any{"start_date": startDate.Format("2006-01-02"), "end_date": endDate.Format("2006-01-02"), "new": len(inserted)},
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":   inserted,
+		"window": businessday.WindowOf(startDate, endDate),
+	})
+}
+
+// yesterday returns the previous business date, as midnight in the business timezone
+func yesterday() time.Time {
+	today, _ := businessday.ParseDate(businessday.DateOf(time.Now()).Format("2006-01-02"))
+	return today.AddDate(0, 0, -1)
+}
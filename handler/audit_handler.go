@@ -0,0 +1,79 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"rekap-backend/apierror"
+	"rekap-backend/config"
+	"rekap-backend/model"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetAuditLogs returns audit log entries, newest first. Owner only.
+// Optional query params: user_id, action, entity, entity_id, start_date, end_date (YYYY-MM-DD,
+// calendar dates), before_id (cursor from next_before_id), limit
+func GetAuditLogs(c *gin.Context) {
+	errs := apierror.FieldErrors{}
+	query := config.DB.WithContext(c).Model(&model.AuditLog{})
+
+	if raw := c.Query("user_id"); raw != "" {
+		if userID, err := strconv.Atoi(raw); err != nil {
+			errs["user_id"] = "must be an integer"
+		} else {
+			query = query.Where("user_id = ?", userID)
+		}
+	}
+	if action := c.Query("action"); action != "" {
+		query = query.Where("action = ?", action)
+	}
+	if entity := c.Query("entity"); entity != "" {
+		query = query.Where("entity = ?", entity)
+	}
+	if entityID := c.Query("entity_id"); entityID != "" {
+		query = query.Where("entity_id = ?", entityID)
+	}
+
+	if window := parseCalendarRangeParams(c, errs); window != nil {
+		query = query.Where("created_at >= ? AND created_at < ?", window.Start, window.End)
+	}
+
+	if raw := c.Query("before_id"); raw != "" {
+		if beforeID, err := strconv.ParseInt(raw, 10, 64); err != nil {
+			errs["before_id"] = "must be an integer"
+		} else {
+			query = query.Where("id < ?", beforeID)
+		}
+	}
+
+	limit := defaultPageSize
+	if raw := c.Query("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 || n > maxPageSize {
+			errs["limit"] = fmt.Sprintf("must be an integer between 1 and %d", maxPageSize)
+		}
+		limit = n
+	}
+
+	if len(errs) > 0 {
+		apierror.InvalidQuery(c, errs)
+		return
+	}
+
+	var logs []model.AuditLog
+	if err := query.Order("id DESC").Limit(limit).Find(&logs).Error; err != nil {
+		apierror.Internal(c, err, "Failed to fetch audit logs")
+		return
+	}
+
+	var nextBeforeID *int64
+	if len(logs) == limit {
+		nextBeforeID = &logs[len(logs)-1].ID
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":           logs,
+		"next_before_id": nextBeforeID,
+	})
+}
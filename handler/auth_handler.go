@@ -1,13 +1,19 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
+	"rekap-backend/apierror"
+	"rekap-backend/audit"
 	"rekap-backend/auth"
 	"rekap-backend/config"
+	"rekap-backend/metrics"
 	"rekap-backend/model"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
 )
 
 type RegisterRequest struct {
@@ -32,27 +38,26 @@ func Register(c *gin.Context) {
 	var req RegisterRequest
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Name, valid email, and password (min 4 chars) are required",
-		})
+		apierror.BindError(c, err)
 		return
 	}
 
 	// Check if email is already registered
 	var existing model.Users
-	if err := config.DB.Where("email = ?", req.Email).First(&existing).Error; err == nil {
-		c.JSON(http.StatusConflict, gin.H{
-			"error": "Email is already registered",
-		})
+	err := config.DB.WithContext(c).Where("email = ?", req.Email).First(&existing).Error
+	if err == nil {
+		apierror.Conflict(c, "Email is already registered")
+		return
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		apierror.Internal(c, err, "Failed to create account")
 		return
 	}
 
 	// Hash password
 	hashed, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to process password",
-		})
+		apierror.Internal(c, err, "Failed to process password")
 		return
 	}
 
@@ -61,25 +66,32 @@ func Register(c *gin.Context) {
 		Name:     req.Name,
 		Email:    req.Email,
 		Password: string(hashed),
+		Role:     model.RoleCashier,
 	}
 
-	if err := config.DB.Create(&user).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to create account",
-		})
+	if err := config.DB.WithContext(c).Create(&user).Error; err != nil {
+		apierror.Fail(c, err, "Failed to create account") // A concurrent registration becomes a 409
 		return
 	}
 
+	audit.Set(c, audit.Entry{
+		Action:   "auth.register",
+		Entity:   "user",
+		EntityID: strconv.Itoa(user.ID),
+		After:    user,
+		UserID:   &user.ID,
+	})
+
 	// Generate tokens so user is logged in immediately after register
-	accessToken, err := auth.GenerateAccessToken(user.ID, user.Email)
+	accessToken, err := auth.GenerateAccessToken(user.ID, user.Email, user.Role)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate access token"})
+		apierror.Internal(c, err, "Failed to generate access token")
 		return
 	}
 
 	refreshToken, err := auth.GenerateRefreshToken(user.ID, user.Email)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate refresh token"})
+		apierror.Internal(c, err, "Failed to generate refresh token")
 		return
 	}
 
@@ -95,45 +107,59 @@ func Login(c *gin.Context) {
 	var req LoginRequest
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Email and password are required",
-		})
+		apierror.BindError(c, err)
 		return
 	}
 
 	// Find user by email
 	var user model.Users
-	result := config.DB.Where("email = ?", req.Email).First(&user)
+	result := config.DB.WithContext(c).Where("email = ?", req.Email).First(&user)
+	if result.Error != nil && !errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		apierror.Internal(c, result.Error, "Failed to log in")
+		return
+	}
 	if result.Error != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"error": "Invalid email or password",
+		metrics.LoginFailed("unknown email")
+		audit.Set(c, audit.Entry{
+			Action:   "auth.login_failed",
+			Entity:   "user",
+			Metadata: map[string]any{"email": req.Email, "reason": "unknown email"},
 		})
+		apierror.Unauthorized(c, "Invalid email or password")
 		return
 	}
 
 	// Verify password
 	err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password))
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"error": "Invalid email or password",
+		metrics.LoginFailed("wrong password")
+		audit.Set(c, audit.Entry{
+			Action:   "auth.login_failed",
+			Entity:   "user",
+			EntityID: strconv.Itoa(user.ID),
+			Metadata: map[string]any{"email": req.Email, "reason": "wrong password"},
 		})
+		apierror.Unauthorized(c, "Invalid email or password")
 		return
 	}
 
+	audit.Set(c, audit.Entry{
+		Action:   "auth.login",
+		Entity:   "user",
+		EntityID: strconv.Itoa(user.ID),
+		UserID:   &user.ID,
+	})
+
 	// Generate tokens
-	accessToken, err := auth.GenerateAccessToken(user.ID, user.Email)
+	accessToken, err := auth.GenerateAccessToken(user.ID, user.Email, user.Role)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to generate access token",
-		})
+		apierror.Internal(c, err, "Failed to generate access token")
 		return
 	}
 
 	refreshToken, err := auth.GenerateRefreshToken(user.ID, user.Email)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to generate refresh token",
-		})
+		apierror.Internal(c, err, "Failed to generate refresh token")
 		return
 	}
 
@@ -151,17 +177,39 @@ func RefreshToken(c *gin.Context) {
 	}
 
 	if err := c.ShouldBindJSON(&body); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "refresh_token is required",
-		})
+		apierror.BindError(c, err)
 		return
 	}
 
-	newAccessToken, err := auth.RefreshAccessToken(body.RefreshToken)
+	claims, err := auth.ValidateRefreshToken(body.RefreshToken)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"error": "Refresh token is invalid or expired",
-		})
+		audit.Set(c, audit.Entry{Action: "auth.refresh_failed", Entity: "user"})
+		apierror.Unauthorized(c, "Refresh token is invalid or expired")
+		return
+	}
+
+	// Reload the user so the new token carries the current role
+	var user model.Users
+	if err := config.DB.WithContext(c).First(&user, claims.UserID).Error; err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			apierror.Internal(c, err, "Failed to refresh token")
+			return
+		}
+		audit.Set(c, audit.Entry{Action: "auth.refresh_failed", Entity: "user", EntityID: strconv.Itoa(claims.UserID)})
+		apierror.Unauthorized(c, "Refresh token is invalid or expired")
+		return
+	}
+
+	audit.Set(c, audit.Entry{
+		Action:   "auth.refresh",
+		Entity:   "user",
+		EntityID: strconv.Itoa(user.ID),
+		UserID:   &user.ID,
+	})
+
+	newAccessToken, err := auth.GenerateAccessToken(user.ID, user.Email, user.Role)
+	if err != nil {
+		apierror.Internal(c, err, "Failed to generate access token")
 		return
 	}
 
@@ -2,36 +2,61 @@ package handler
 
 import (
 	"net/http"
+	"rekap-backend/apierror"
+	"rekap-backend/cache"
 	"rekap-backend/config"
+	"rekap-backend/stats"
 
 	"github.com/gin-gonic/gin"
 )
 
 // BranchResult holds aggregated statistics per branch
 type BranchResult struct {
-	BranchID          int     `json:"branch_id"`
-	TotalTransactions int64   `json:"total_transactions"`
-	TotalRevenue      float64 `json:"total_revenue"`
+	BranchID          int   `json:"branch_id"`
+	TotalTransactions int64 `json:"total_transactions"`
+	TotalRevenue      int64 `json:"total_revenue"` // Whole rupiah
 }
 
-// GetBranches returns a list of branches with their transaction statistics
+// GetBranches returns a list of branches with their transaction statistics.
+// Optional query param: source (rollup or live, see parseSourceParam)
 func GetBranches(c *gin.Context) {
+	errs := apierror.FieldErrors{}
+	source := parseSourceParam(c, errs)
+	if len(errs) > 0 {
+		apierror.InvalidQuery(c, errs)
+		return
+	}
+
+	// Totals span every branch and date
+	cache.SetScope(c, cache.Scope{})
+
 	var branches []BranchResult
 
-	result := config.DB.Table("transactions").
+	query := config.ReadDB.WithContext(c).Table("daily_branch_stats").
 		Select(`
 			branch_id,
-			COUNT(*) as total_transactions,
-			COALESCE(SUM(total), 0) as total_revenue
-		`).
+			SUM(total_transactions)::BIGINT as total_transactions,
+			SUM(total_revenue)::BIGINT as total_revenue
+		`)
+	if source == stats.SourceLive {
+		query = config.ReadDB.WithContext(c).Table("transactions").
+			Select(`
+				branch_id,
+				COUNT(*) as total_transactions,
+				COALESCE(SUM(total), 0)::BIGINT as total_revenue
+			`).
+			Where("deleted_at IS NULL")
+	}
+
+	result := query.
 		Group("branch_id").
 		Order("branch_id ASC").
 		Scan(&branches)
 
 	if result.Error != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch branches"})
+		apierror.Internal(c, result.Error, "Failed to fetch branches")
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"data": branches})
+	c.JSON(http.StatusOK, gin.H{"data": branches, "source": source})
 }
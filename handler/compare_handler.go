@@ -0,0 +1,279 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"rekap-backend/apierror"
+	"rekap-backend/businessday"
+	"rekap-backend/cache"
+	"rekap-backend/config"
+	"rekap-backend/stats"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxComparisonDays caps each side of a comparison; every day becomes a bucket in every series
+const maxComparisonDays = 366
+
+// PeriodMetrics holds the compared totals of one bucket or period. Revenue is whole rupiah.
+type PeriodMetrics struct {
+	TotalTransactions int64   `json:"total_transactions"`
+	TotalRevenue      int64   `json:"total_revenue"`
+	TotalKg           float64 `json:"total_kg"`
+	TotalPc           int64   `json:"total_pc"`
+}
+
+// MetricDelta is the change of one metric from the comparison period to the current one
+type MetricDelta struct {
+	Absolute float64  `json:"absolute"`
+	Percent  *float64 `json:"percent"` // Nil when the comparison value is zero
+}
+
+// MetricsDelta holds the deltas of every compared metric
+type MetricsDelta struct {
+	TotalTransactions MetricDelta `json:"total_transactions"`
+	TotalRevenue      MetricDelta `json:"total_revenue"`
+	TotalKg           MetricDelta `json:"total_kg"`
+	TotalPc           MetricDelta `json:"total_pc"`
+}
+
+// ComparisonBucket aligns the n-th business day of both periods
+type ComparisonBucket struct {
+	Index          int           `json:"index"`
+	Date           *string       `json:"date"`            // Nil when the current period is shorter
+	ComparisonDate *string       `json:"comparison_date"` // Nil when the comparison period is shorter
+	Current        PeriodMetrics `json:"current"`
+	Comparison     PeriodMetrics `json:"comparison"`
+	Delta          MetricsDelta  `json:"delta"`
+}
+
+// ComparisonSeries is the aligned comparison for one branch, or all branches consolidated
+type ComparisonSeries struct {
+	BranchID   *int               `json:"branch_id"` // Nil for the consolidated series
+	Current    PeriodMetrics      `json:"current"`
+	Comparison PeriodMetrics      `json:"comparison"`
+	Delta      MetricsDelta       `json:"delta"`
+	Buckets    []ComparisonBucket `json:"buckets"`
+}
+
+// Period describes one side of the comparison
+type Period struct {
+	StartDate string             `json:"start_date"`
+	EndDate   string             `json:"end_date"`
+	Window    businessday.Window `json:"window"`
+}
+
+// GetSummaryComparison compares a date range with another period, bucket by business day,
+// per branch and consolidated.
+// Query params: start_date, end_date (YYYY-MM-DD), compare = previous (default, the same number of
+// days right before), last_year (same dates one year earlier) or custom (with compare_start_date
+// and compare_end_date). Optional: branch_id (repeatable), source (rollup or live).
+// Each period covers at most maxComparisonDays.
+func GetSummaryComparison(c *gin.Context) {
+	errs := apierror.FieldErrors{}
+
+	startDate, endDate, ok := parseDates(c, errs)
+	if !ok && len(errs) == 0 {
+		errs["start_date"] = "is required"
+		errs["end_date"] = "is required"
+	}
+	if ok && daysBetween(startDate, endDate) > maxComparisonDays {
+		errs["end_date"] = fmt.Sprintf("must be at most %d days from start_date", maxComparisonDays-1)
+	}
+	branchIDs := parseBranchIDsParam(c, errs)
+	source := parseSourceParam(c, errs)
+
+	var compareStart, compareEnd time.Time
+	mode := c.DefaultQuery("compare", "previous")
+	switch mode {
+	case "previous":
+		days := daysBetween(startDate, endDate)
+		compareStart = startDate.AddDate(0, 0, -days)
+		compareEnd = startDate.AddDate(0, 0, -1)
+	case "last_year":
+		compareStart = startDate.AddDate(-1, 0, 0)
+		compareEnd = endDate.AddDate(-1, 0, 0)
+	case "custom":
+		var err error
+		if compareStart, err = businessday.ParseDate(c.Query("compare_start_date")); err != nil {
+			errs["compare_start_date"] = "is required for compare=custom, use: YYYY-MM-DD"
+		}
+		if compareEnd, err = businessday.ParseDate(c.Query("compare_end_date")); err != nil {
+			errs["compare_end_date"] = "is required for compare=custom, use: YYYY-MM-DD"
+		} else if compareEnd.Before(compareStart) {
+			errs["compare_end_date"] = "must not be before compare_start_date"
+		} else if daysBetween(compareStart, compareEnd) > maxComparisonDays {
+			errs["compare_end_date"] = fmt.Sprintf("must be at most %d days from compare_start_date", maxComparisonDays-1)
+		}
+	default:
+		errs["compare"] = "must be one of: previous, last_year, custom"
+	}
+
+	if len(errs) > 0 {
+		apierror.InvalidQuery(c, errs)
+		return
+	}
+
+	current := newPeriod(startDate, endDate)
+	comparison := newPeriod(compareStart, compareEnd)
+
+	cache.SetScope(c, cache.Scope{
+		BranchIDs: branchIDs,
+		Ranges: []cache.DateRange{
+			{From: current.StartDate, To: current.EndDate},
+			{From: comparison.StartDate, To: comparison.EndDate},
+		},
+	})
+
+	currentRows, err := stats.Query(config.ReadDB.WithContext(c), source, startDate, endDate, branchIDs)
+	if err != nil {
+		apierror.Internal(c, err, "Failed to build comparison")
+		return
+	}
+	comparisonRows, err := stats.Query(config.ReadDB.WithContext(c), source, compareStart, compareEnd, branchIDs)
+	if err != nil {
+		apierror.Internal(c, err, "Failed to build comparison")
+		return
+	}
+
+	currentDates := datesBetween(startDate, endDate)
+	comparisonDates := datesBetween(compareStart, compareEnd)
+
+	// Branches seen in either period, plus every requested one
+	branchSet := map[int]bool{}
+	for _, id := range branchIDs {
+		branchSet[id] = true
+	}
+	for _, row := range append(currentRows, comparisonRows...) {
+		branchSet[row.BranchID] = true
+	}
+	branches := make([]int, 0, len(branchSet))
+	for id := range branchSet {
+		branches = append(branches, id)
+	}
+	sort.Ints(branches)
+
+	perBranch := make([]ComparisonSeries, 0, len(branches))
+	for _, id := range branches {
+		branchID := id
+		series := buildComparisonSeries(
+			indexDaily(currentRows, &branchID), indexDaily(comparisonRows, &branchID),
+			currentDates, comparisonDates,
+		)
+		series.BranchID = &branchID
+		perBranch = append(perBranch, series)
+	}
+
+	consolidated := buildComparisonSeries(
+		indexDaily(currentRows, nil), indexDaily(comparisonRows, nil),
+		currentDates, comparisonDates,
+	)
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": gin.H{
+			"consolidated": consolidated,
+			"branches":     perBranch,
+		},
+		"compare":    mode,
+		"current":    current,
+		"comparison": comparison,
+		"source":     source,
+	})
+}
+
+func newPeriod(start, end time.Time) Period {
+	return Period{
+		StartDate: start.Format("2006-01-02"),
+		EndDate:   end.Format("2006-01-02"),
+		Window:    businessday.WindowOf(start, end),
+	}
+}
+
+// daysBetween counts the business dates start..end, inclusive
+func daysBetween(start, end time.Time) int {
+	return int(end.Sub(start).Hours()/24+0.5) + 1
+}
+
+// datesBetween lists the business dates start..end (inclusive) as YYYY-MM-DD
+func datesBetween(start, end time.Time) []string {
+	var dates []string
+	for d := start; !d.After(end); d = d.AddDate(0, 0, 1) {
+		dates = append(dates, d.Format("2006-01-02"))
+	}
+	return dates
+}
+
+// indexDaily sums daily rows by date, for one branch or (branchID nil) all of them
+func indexDaily(rows []stats.Daily, branchID *int) map[string]PeriodMetrics {
+	byDate := map[string]PeriodMetrics{}
+	for _, row := range rows {
+		if branchID != nil && row.BranchID != *branchID {
+			continue
+		}
+		m := byDate[row.Date]
+		m.TotalTransactions += row.TotalTransactions
+		m.TotalRevenue += row.TotalRevenue
+		m.TotalKg += row.TotalKg
+		m.TotalPc += row.TotalPc
+		byDate[row.Date] = m
+	}
+	return byDate
+}
+
+// buildComparisonSeries aligns both periods day by day and totals them
+func buildComparisonSeries(current, comparison map[string]PeriodMetrics, currentDates, comparisonDates []string) ComparisonSeries {
+	length := len(currentDates)
+	if len(comparisonDates) > length {
+		length = len(comparisonDates)
+	}
+
+	series := ComparisonSeries{Buckets: make([]ComparisonBucket, 0, length)}
+	for i := 0; i < length; i++ {
+		bucket := ComparisonBucket{Index: i}
+		if i < len(currentDates) {
+			bucket.Date = &currentDates[i]
+			bucket.Current = current[currentDates[i]]
+		}
+		if i < len(comparisonDates) {
+			bucket.ComparisonDate = &comparisonDates[i]
+			bucket.Comparison = comparison[comparisonDates[i]]
+		}
+		bucket.Delta = deltaOf(bucket.Current, bucket.Comparison)
+		series.Buckets = append(series.Buckets, bucket)
+
+		series.Current = addMetrics(series.Current, bucket.Current)
+		series.Comparison = addMetrics(series.Comparison, bucket.Comparison)
+	}
+	series.Delta = deltaOf(series.Current, series.Comparison)
+
+	return series
+}
+
+func addMetrics(a, b PeriodMetrics) PeriodMetrics {
+	return PeriodMetrics{
+		TotalTransactions: a.TotalTransactions + b.TotalTransactions,
+		TotalRevenue:      a.TotalRevenue + b.TotalRevenue,
+		TotalKg:           a.TotalKg + b.TotalKg,
+		TotalPc:           a.TotalPc + b.TotalPc,
+	}
+}
+
+func deltaOf(current, comparison PeriodMetrics) MetricsDelta {
+	return MetricsDelta{
+		TotalTransactions: metricDelta(float64(current.TotalTransactions), float64(comparison.TotalTransactions)),
+		TotalRevenue:      metricDelta(float64(current.TotalRevenue), float64(comparison.TotalRevenue)),
+		TotalKg:           metricDelta(current.TotalKg, comparison.TotalKg),
+		TotalPc:           metricDelta(float64(current.TotalPc), float64(comparison.TotalPc)),
+	}
+}
+
+func metricDelta(current, comparison float64) MetricDelta {
+	delta := MetricDelta{Absolute: current - comparison}
+	if comparison != 0 {
+		percent := (current - comparison) / comparison * 100
+		delta.Percent = &percent
+	}
+	return delta
+}
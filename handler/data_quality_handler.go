@@ -0,0 +1,51 @@
+package handler
+
+import (
+	"net/http"
+	"rekap-backend/apierror"
+	"rekap-backend/audit"
+	"rekap-backend/cache"
+	"rekap-backend/config"
+	"rekap-backend/dataquality"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetDataQualityReport scans transactions for consistency rule violations. Owner only.
+func GetDataQualityReport(c *gin.Context) {
+	report, err := dataquality.Scan(config.DB.WithContext(c))
+	if err != nil {
+		apierror.Internal(c, err, "Failed to run data quality checks")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": report})
+}
+
+// FixDataQuality applies the safe automatic fixes. Owner only.
+// Query param: dry_run (default true) lists the changes without writing them
+func FixDataQuality(c *gin.Context) {
+	dryRun, err := strconv.ParseBool(c.DefaultQuery("dry_run", "true"))
+	if err != nil {
+		apierror.InvalidQuery(c, apierror.FieldErrors{"dry_run": "must be true or false"})
+		return
+	}
+
+	result, err := dataquality.Fix(config.DB.WithContext(c), dryRun)
+	if err != nil {
+		apierror.Internal(c, err, "Failed to apply data quality fixes")
+		return
+	}
+	if !dryRun && result.Fixed > 0 {
+		cache.InvalidateAll()
+	}
+
+	audit.Set(c, audit.Entry{
+		Action:   "data_quality.fix",
+		Entity:   "transaction",
+		Metadata: map[string]any{"dry_run": dryRun, "fixed": result.Fixed},
+	})
+
+	c.JSON(http.StatusOK, gin.H{"data": result})
+}
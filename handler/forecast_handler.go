@@ -0,0 +1,157 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"rekap-backend/apierror"
+	"rekap-backend/businessday"
+	"rekap-backend/config"
+	"rekap-backend/forecast"
+	"rekap-backend/stats"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Forecast limits, in days
+const (
+	defaultForecastDays = 14
+	maxForecastDays     = 90
+	defaultHistoryDays  = 180
+	minHistoryDays      = 28
+	maxHistoryDays      = 730
+	seasonLength        = 7 // Weekly seasonality
+)
+
+// ForecastDay is one projected business date
+type ForecastDay struct {
+	Date string `json:"date"`
+	forecast.Point
+}
+
+// MetricForecast is the projection of one metric with its backtest error
+type MetricForecast struct {
+	Forecast []ForecastDay      `json:"forecast"`
+	Params   *forecast.Params   `json:"params"`
+	Backtest *forecast.Accuracy `json:"backtest"` // Nil when the history is too short to hold out the horizon
+	Error    string             `json:"error,omitempty"`
+}
+
+// BranchForecast holds the projections of one branch
+type BranchForecast struct {
+	BranchID int            `json:"branch_id"`
+	Revenue  MetricForecast `json:"revenue"` // Whole rupiah per day
+	Kg       MetricForecast `json:"kg"`
+	Orders   MetricForecast `json:"orders"`
+}
+
+// GetForecast projects the next N business days of revenue, kg and order count per branch with
+// a weekly Holt-Winters model, returning 95% prediction intervals and a holdout backtest.
+// Optional query params: branch_id (repeatable, default every branch with history),
+// days (default 14, max 90), history_days (default 180, 28-730)
+func GetForecast(c *gin.Context) {
+	errs := apierror.FieldErrors{}
+	branchIDs := parseBranchIDsParam(c, errs)
+	days := parseIntParam(c, "days", defaultForecastDays, 1, maxForecastDays, errs)
+	historyDays := parseIntParam(c, "history_days", defaultHistoryDays, minHistoryDays, maxHistoryDays, errs)
+
+	if len(errs) > 0 {
+		apierror.InvalidQuery(c, errs)
+		return
+	}
+
+	// History runs up to yesterday, today is still incomplete
+	today, _ := businessday.ParseDate(businessday.DateOf(time.Now()).Format("2006-01-02"))
+	historyEnd := today.AddDate(0, 0, -1)
+	historyStart := today.AddDate(0, 0, -historyDays)
+	window := businessday.WindowOf(historyStart, historyEnd)
+
+	rows, err := stats.QueryDaily(config.ReadDB.WithContext(c), window, branchIDs)
+	if err != nil {
+		apierror.Internal(c, err, "Failed to load history")
+		return
+	}
+
+	// Zero-filled daily series per branch
+	dates := datesBetween(historyStart, historyEnd)
+	dateIndex := make(map[string]int, len(dates))
+	for i, d := range dates {
+		dateIndex[d] = i
+	}
+
+	type series struct{ revenue, kg, orders []float64 }
+	byBranch := map[int]*series{}
+	for _, id := range branchIDs {
+		byBranch[id] = &series{make([]float64, len(dates)), make([]float64, len(dates)), make([]float64, len(dates))}
+	}
+	for _, row := range rows {
+		s, ok := byBranch[row.BranchID]
+		if !ok {
+			s = &series{make([]float64, len(dates)), make([]float64, len(dates)), make([]float64, len(dates))}
+			byBranch[row.BranchID] = s
+		}
+		i := dateIndex[row.Date]
+		s.revenue[i] = float64(row.TotalRevenue)
+		s.kg[i] = row.TotalKg
+		s.orders[i] = float64(row.TotalTransactions)
+	}
+
+	forecastDates := datesBetween(today, today.AddDate(0, 0, days-1))
+
+	results := make([]BranchForecast, 0, len(byBranch))
+	for id, s := range byBranch {
+		results = append(results, BranchForecast{
+			BranchID: id,
+			Revenue:  forecastMetric(s.revenue, days, forecastDates),
+			Kg:       forecastMetric(s.kg, days, forecastDates),
+			Orders:   forecastMetric(s.orders, days, forecastDates),
+		})
+	}
+	sort.Slice(results, func(i, j int) bool { return results[i].BranchID < results[j].BranchID })
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":           results,
+		"history_window": window,
+		"days":           days,
+		"interval":       "95%",
+	})
+}
+
+// forecastMetric fits one series, projects it and backtests on the last `days` values
+func forecastMetric(values []float64, days int, dates []string) MetricForecast {
+	model, err := forecast.Fit(values, seasonLength)
+	if err != nil {
+		return MetricForecast{Forecast: []ForecastDay{}, Error: err.Error()}
+	}
+
+	points := model.Forecast(days)
+	result := MetricForecast{
+		Forecast: make([]ForecastDay, len(points)),
+		Params:   &model.Params,
+	}
+	for i, p := range points {
+		result.Forecast[i] = ForecastDay{Date: dates[i], Point: p}
+	}
+
+	if acc, err := forecast.Backtest(values, seasonLength, days); err == nil {
+		result.Backtest = &acc
+	}
+	return result
+}
+
+// parseIntParam parses an optional integer query param within [min, max]
+func parseIntParam(c *gin.Context, name string, def, min, max int, errs apierror.FieldErrors) int {
+	raw := c.Query(name)
+	if raw == "" {
+		return def
+	}
+
+	n, err := strconv.Atoi(raw)
+	if err != nil || n < min || n > max {
+		errs[name] = fmt.Sprintf("must be an integer between %d and %d", min, max)
+		return def
+	}
+	return n
+}
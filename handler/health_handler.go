@@ -0,0 +1,104 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"rekap-backend/config"
+	"rekap-backend/logging"
+	"rekap-backend/migration"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// readinessCheckTimeout bounds each dependency check of /readyz
+const readinessCheckTimeout = 2 * time.Second
+
+// draining is set once shutdown starts, so /readyz takes the instance out of rotation while
+// in-flight requests finish
+var draining atomic.Bool
+
+// MarkDraining makes /readyz report the instance as not ready from now on
+func MarkDraining() {
+	draining.Store(true)
+}
+
+// CheckResult is the outcome of one readiness check. The probe is public, so Error is a fixed
+// reason; the underlying error is only logged.
+type CheckResult struct {
+	Status string `json:"status"` // "ok" or "fail"
+	Error  string `json:"error,omitempty"`
+	cause  error
+}
+
+// Healthz is the liveness probe: the process is up and serving HTTP. It does not touch the
+// database, so an outage does not get healthy instances restarted.
+func Healthz(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// Readyz is the readiness probe: the database (and replica, if any) answers and every migration
+// is applied. Responds 503 with the failing checks, or while draining.
+func Readyz(c *gin.Context) {
+	checks := map[string]CheckResult{
+		"database":   checkPing(c.Request.Context(), config.DB),
+		"migrations": checkMigrations(c.Request.Context()),
+	}
+	if config.ReadDB != config.DB {
+		checks["replica"] = checkPing(c.Request.Context(), config.ReadDB)
+	}
+
+	ready := true
+	for name, check := range checks {
+		if check.Status != "ok" {
+			ready = false
+			logging.For(c).Warn("Readiness check failed", "check", name, "error", check.cause)
+		}
+	}
+
+	status, code := "ok", http.StatusOK
+	if draining.Load() {
+		status, code = "draining", http.StatusServiceUnavailable
+	} else if !ready {
+		status, code = "fail", http.StatusServiceUnavailable
+	}
+
+	c.JSON(code, gin.H{
+		"status": status,
+		"checks": checks,
+	})
+}
+
+// checkPing pings a connection pool
+func checkPing(ctx context.Context, db *gorm.DB) CheckResult {
+	ctx, cancel := context.WithTimeout(ctx, readinessCheckTimeout)
+	defer cancel()
+
+	sqlDB, err := db.DB()
+	if err == nil {
+		err = sqlDB.PingContext(ctx)
+	}
+	if err != nil {
+		return CheckResult{Status: "fail", Error: "unreachable", cause: err}
+	}
+	return CheckResult{Status: "ok"}
+}
+
+// checkMigrations fails while any embedded migration is not applied
+func checkMigrations(ctx context.Context) CheckResult {
+	ctx, cancel := context.WithTimeout(ctx, readinessCheckTimeout)
+	defer cancel()
+
+	pending, err := migration.Pending(config.DB.WithContext(ctx))
+	if err != nil {
+		return CheckResult{Status: "fail", Error: "check failed", cause: err}
+	}
+	if len(pending) > 0 {
+		return CheckResult{Status: "fail", Error: "pending", cause: errors.New(strings.Join(pending, ", "))}
+	}
+	return CheckResult{Status: "ok"}
+}
@@ -0,0 +1,79 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/rand/v2"
+	"rekap-backend/model"
+	"strconv"
+	"testing"
+)
+
+// maxSafeFloat is 2^53, above which float64 cannot hold every integer
+const maxSafeFloat = int64(1) << 53
+
+// randomAmount returns a whole-rupiah amount, a third of them above 2^53
+func randomAmount(r *rand.Rand) int64 {
+	switch r.IntN(3) {
+	case 0:
+		return r.Int64N(10_000_000) // An everyday receipt
+	case 1:
+		return r.Int64N(maxSafeFloat)
+	default:
+		return maxSafeFloat + 1 + r.Int64N(maxSafeFloat)
+	}
+}
+
+func TestMoneyJSONRoundTrip(t *testing.T) {
+	r := rand.New(rand.NewPCG(33, 2))
+
+	for i := 0; i < 1000; i++ {
+		in := model.Transaction{
+			DP:               randomAmount(r),
+			Pelunasan:        randomAmount(r),
+			Subtotal:         randomAmount(r),
+			BiayaAntarJemput: randomAmount(r),
+			Diskon:           randomAmount(r),
+			DiskonPoin:       randomAmount(r),
+			Total:            randomAmount(r),
+		}
+		raw, err := json.Marshal(in)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var out model.Transaction
+		if err := json.Unmarshal(raw, &out); err != nil {
+			t.Fatal(err)
+		}
+		if out.DP != in.DP || out.Pelunasan != in.Pelunasan || out.Subtotal != in.Subtotal ||
+			out.BiayaAntarJemput != in.BiayaAntarJemput || out.Diskon != in.Diskon ||
+			out.DiskonPoin != in.DiskonPoin || out.Total != in.Total {
+			t.Fatalf("round trip changed amounts:\n in  %+v\n out %+v", in, out)
+		}
+
+		// Amounts are encoded as JSON integers, digit for digit
+		var fields map[string]any
+		dec := json.NewDecoder(bytes.NewReader(raw))
+		dec.UseNumber()
+		if err := dec.Decode(&fields); err != nil {
+			t.Fatal(err)
+		}
+		if got, want := fields["total"].(json.Number).String(), strconv.FormatInt(in.Total, 10); got != want {
+			t.Fatalf("total encoded as %s, want %s", got, want)
+		}
+	}
+
+	result := DailySummaryResult{TotalRevenue: maxSafeFloat + 1}
+	raw, err := json.Marshal(result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var back DailySummaryResult
+	if err := json.Unmarshal(raw, &back); err != nil {
+		t.Fatal(err)
+	}
+	if back.TotalRevenue != maxSafeFloat+1 {
+		t.Errorf("total_revenue = %d after a round trip, want %d", back.TotalRevenue, maxSafeFloat+1)
+	}
+}
@@ -0,0 +1,218 @@
+package handler
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"rekap-backend/apierror"
+	"rekap-backend/model"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Page size limits for keyset-paginated listings
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// Page describes the position of one page in a keyset-paginated listing.
+// Pass next_cursor or prev_cursor back as ?cursor= to move between pages.
+type Page struct {
+	Limit      int     `json:"limit"`
+	NextCursor *string `json:"next_cursor"`
+	PrevCursor *string `json:"prev_cursor"`
+	Total      *int64  `json:"total,omitempty"` // Only set with ?include_total=true
+}
+
+// pageCursor is the decoded form of an opaque cursor: the boundary row's sort key and ID
+type pageCursor struct {
+	Sort  string          `json:"s"`
+	Value json.RawMessage `json:"v"`
+	ID    int             `json:"id"`
+	Prev  bool            `json:"p,omitempty"` // Page backwards from the boundary row
+}
+
+// pageRequest holds the validated pagination query params
+type pageRequest struct {
+	Limit        int
+	Cursor       *pageCursor
+	IncludeTotal bool
+}
+
+// parsePageRequest reads limit, cursor and include_total, recording invalid values in errs
+func parsePageRequest(c *gin.Context, errs apierror.FieldErrors) pageRequest {
+	req := pageRequest{Limit: defaultPageSize}
+
+	if raw := c.Query("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit < 1 || limit > maxPageSize {
+			errs["limit"] = fmt.Sprintf("must be an integer between 1 and %d", maxPageSize)
+		} else {
+			req.Limit = limit
+		}
+	}
+
+	if raw := c.Query("cursor"); raw != "" {
+		cur, err := decodeCursor(raw)
+		if err != nil {
+			errs["cursor"] = "is invalid, use next_cursor or prev_cursor from a previous response"
+		} else {
+			req.Cursor = cur
+		}
+	}
+
+	if raw := c.Query("include_total"); raw != "" {
+		include, err := strconv.ParseBool(raw)
+		if err != nil {
+			errs["include_total"] = "must be true or false"
+		}
+		req.IncludeTotal = include
+	}
+
+	return req
+}
+
+func encodeCursor(cur pageCursor) string {
+	raw, _ := json.Marshal(cur)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func decodeCursor(s string) (*pageCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+
+	var cur pageCursor
+	if err := json.Unmarshal(raw, &cur); err != nil {
+		return nil, err
+	}
+	if len(cur.Value) == 0 || cur.ID < 1 {
+		return nil, errors.New("incomplete cursor")
+	}
+	return &cur, nil
+}
+
+// transactionSortKey returns the value of the sort column for a transaction
+func transactionSortKey(sort string, t model.Transaction) any {
+	switch sort {
+	case "no_transaksi":
+		return t.NoTransaksi
+	case "nama_pelanggan":
+		return t.NamaPelanggan
+	case "total":
+		return t.Total
+	case "jumlah_kg":
+		return t.JumlahKg
+	case "created_at":
+		return t.CreatedAt
+	default:
+		return t.TanggalMasuk
+	}
+}
+
+// decodeSortKey converts a cursor value back into the Go type of the sort column
+func decodeSortKey(sort string, raw json.RawMessage) (any, error) {
+	switch sort {
+	case "no_transaksi", "nama_pelanggan":
+		var v string
+		err := json.Unmarshal(raw, &v)
+		return v, err
+	case "total":
+		var v int64
+		err := json.Unmarshal(raw, &v)
+		return v, err
+	case "jumlah_kg":
+		var v float64
+		err := json.Unmarshal(raw, &v)
+		return v, err
+	default:
+		var v time.Time
+		err := json.Unmarshal(raw, &v)
+		return v, err
+	}
+}
+
+// errCursorMismatch is returned when a cursor was issued for a different sort
+var errCursorMismatch = errors.New("cursor does not match the requested sort")
+
+// paginateTransactions fetches one page of transactions ordered by (sort column, id).
+// The query must already carry its filters; it is not modified.
+func paginateTransactions(query *gorm.DB, sort, order string, req pageRequest) ([]model.Transaction, Page, error) {
+	page := Page{Limit: req.Limit}
+	base := query.Session(&gorm.Session{})
+
+	if req.IncludeTotal {
+		var total int64
+		if err := base.Count(&total).Error; err != nil {
+			return nil, page, err
+		}
+		page.Total = &total
+	}
+
+	column := transactionSortColumns[sort]
+	descending := order == "desc"
+
+	// Walking backwards flips the comparison and the order, the rows are reversed afterwards
+	backwards := req.Cursor != nil && req.Cursor.Prev
+	scanDescending := descending != backwards
+
+	direction, comparison := "ASC", ">"
+	if scanDescending {
+		direction, comparison = "DESC", "<"
+	}
+
+	q := base.Order(column + " " + direction + ", id " + direction)
+	if req.Cursor != nil {
+		if req.Cursor.Sort != sort {
+			return nil, page, errCursorMismatch
+		}
+		value, err := decodeSortKey(sort, req.Cursor.Value)
+		if err != nil {
+			return nil, page, errCursorMismatch
+		}
+		q = q.Where("("+column+", id) "+comparison+" (?, ?)", value, req.Cursor.ID)
+	}
+
+	// Fetch one extra row to know whether another page follows
+	var rows []model.Transaction
+	if err := q.Limit(req.Limit + 1).Find(&rows).Error; err != nil {
+		return nil, page, err
+	}
+
+	hasMore := len(rows) > req.Limit
+	if hasMore {
+		rows = rows[:req.Limit]
+	}
+	if backwards {
+		for i, j := 0, len(rows)-1; i < j; i, j = i+1, j-1 {
+			rows[i], rows[j] = rows[j], rows[i]
+		}
+	}
+
+	if len(rows) == 0 {
+		return rows, page, nil
+	}
+
+	cursorFor := func(t model.Transaction, prev bool) *string {
+		value, _ := json.Marshal(transactionSortKey(sort, t))
+		s := encodeCursor(pageCursor{Sort: sort, Value: value, ID: t.ID, Prev: prev})
+		return &s
+	}
+
+	// Moving forward there is a next page when an extra row came back, and a previous page
+	// whenever we started from a cursor. Moving backwards it is the other way round.
+	if (!backwards && hasMore) || backwards {
+		page.NextCursor = cursorFor(rows[len(rows)-1], false)
+	}
+	if (backwards && hasMore) || (!backwards && req.Cursor != nil) {
+		page.PrevCursor = cursorFor(rows[0], true)
+	}
+
+	return rows, page, nil
+}
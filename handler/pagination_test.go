@@ -0,0 +1,137 @@
+package handler
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http/httptest"
+	"reflect"
+	"rekap-backend/apierror"
+	"rekap-backend/model"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	trx := model.Transaction{
+		ID:            812,
+		NoTransaksi:   "TRX/03/260116/01444",
+		NamaPelanggan: "Budi \"Laundry\" Santoso",
+		Total:         1<<53 + 1,
+		JumlahKg:      3.75,
+		TanggalMasuk:  time.Date(2026, 1, 16, 9, 12, 3, 500, time.FixedZone("WIB", 7*3600)),
+		CreatedAt:     time.Date(2026, 1, 16, 2, 12, 3, 0, time.UTC),
+	}
+
+	for sort := range transactionSortColumns {
+		for _, prev := range []bool{false, true} {
+			want := transactionSortKey(sort, trx)
+			value, err := json.Marshal(want)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			cur, err := decodeCursor(encodeCursor(pageCursor{Sort: sort, Value: value, ID: trx.ID, Prev: prev}))
+			if err != nil {
+				t.Fatalf("%s: decode: %v", sort, err)
+			}
+			if cur.Sort != sort || cur.ID != trx.ID || cur.Prev != prev {
+				t.Errorf("%s: got cursor %+v", sort, cur)
+			}
+
+			got, err := decodeSortKey(sort, cur.Value)
+			if err != nil {
+				t.Fatalf("%s: sort key: %v", sort, err)
+			}
+			if wantTime, ok := want.(time.Time); ok {
+				if !wantTime.Equal(got.(time.Time)) {
+					t.Errorf("%s: sort key %v, want %v", sort, got, want)
+				}
+			} else if !reflect.DeepEqual(got, want) {
+				t.Errorf("%s: sort key %#v, want %#v", sort, got, want)
+			}
+		}
+	}
+}
+
+func TestDecodeCursorRejectsMalformed(t *testing.T) {
+	encode := func(s string) string { return base64.RawURLEncoding.EncodeToString([]byte(s)) }
+
+	tests := map[string]string{
+		"empty":          "",
+		"not base64":     "not a cursor!",
+		"padded base64":  base64.URLEncoding.EncodeToString([]byte(`{"s":"total","v":1,"id":1}`)),
+		"not JSON":       encode("total:1:1"),
+		"JSON array":     encode(`["total",1,1]`),
+		"missing value":  encode(`{"s":"total","id":5}`),
+		"missing id":     encode(`{"s":"total","v":100}`),
+		"zero id":        encode(`{"s":"total","v":100,"id":0}`),
+		"negative id":    encode(`{"s":"total","v":100,"id":-3}`),
+		"id as string":   encode(`{"s":"total","v":100,"id":"5"}`),
+		"truncated JSON": encode(`{"s":"total","v":100,"id":5`),
+	}
+	for name, raw := range tests {
+		if cur, err := decodeCursor(raw); err == nil {
+			t.Errorf("%s: decoded %+v, want an error", name, cur)
+		}
+	}
+}
+
+func TestDecodeSortKeyRejectsTamperedValues(t *testing.T) {
+	// Well-formed cursors whose value was edited to the wrong type for the sort
+	tests := []struct {
+		sort  string
+		value string
+	}{
+		{"total", `"1000"`},
+		{"total", `12.5`},
+		{"jumlah_kg", `"heavy"`},
+		{"tanggal_masuk", `"2026-01-16"`},
+		{"created_at", `12345`},
+		{"no_transaksi", `42`},
+		{"nama_pelanggan", `{"a":1}`},
+	}
+	for _, tt := range tests {
+		if v, err := decodeSortKey(tt.sort, json.RawMessage(tt.value)); err == nil {
+			t.Errorf("%s with %s: decoded %#v, want an error", tt.sort, tt.value, v)
+		}
+	}
+}
+
+func TestParsePageRequest(t *testing.T) {
+	valid := encodeCursor(pageCursor{Sort: "total", Value: json.RawMessage(`100`), ID: 5})
+
+	tests := []struct {
+		query     string
+		wantLimit int
+		wantErrs  []string
+	}{
+		{"", defaultPageSize, nil},
+		{"limit=50&include_total=true&cursor=" + valid, 50, nil},
+		{"limit=0", defaultPageSize, []string{"limit"}},
+		{"limit=101", defaultPageSize, []string{"limit"}},
+		{"limit=ten", defaultPageSize, []string{"limit"}},
+		{"cursor=garbage", defaultPageSize, []string{"cursor"}},
+		{"cursor=" + valid + "x", defaultPageSize, []string{"cursor"}},
+		{"include_total=maybe", defaultPageSize, []string{"include_total"}},
+	}
+	for _, tt := range tests {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest("GET", "/api/transactions?"+tt.query, nil)
+
+		errs := apierror.FieldErrors{}
+		req := parsePageRequest(c, errs)
+		if req.Limit != tt.wantLimit {
+			t.Errorf("%q: limit %d, want %d", tt.query, req.Limit, tt.wantLimit)
+		}
+		if len(errs) != len(tt.wantErrs) {
+			t.Errorf("%q: errors %v, want %v", tt.query, errs, tt.wantErrs)
+		}
+		for _, field := range tt.wantErrs {
+			if _, ok := errs[field]; !ok {
+				t.Errorf("%q: no error for %s in %v", tt.query, field, errs)
+			}
+		}
+	}
+}
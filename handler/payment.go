@@ -0,0 +1,34 @@
+package handler
+
+import (
+	"rekap-backend/model"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// recordPayment adds a row to the payments ledger
+func recordPayment(tx *gorm.DB, t model.Transaction, amount int64, kind string, userID int) error {
+	payment := model.Payment{
+		TransactionID: t.ID,
+		BranchID:      t.BranchID,
+		Amount:        amount,
+		Kind:          kind,
+		ReceivedAt:    time.Now(),
+	}
+	if userID != 0 {
+		payment.ReceivedBy = &userID
+	}
+	return tx.Create(&payment).Error
+}
+
+// settledThroughLedger returns how much of a transaction's pelunasan was taken at the counter
+// (pelunasan payments minus reversals). Imported settlements have no ledger rows.
+func settledThroughLedger(tx *gorm.DB, transactionID int) (int64, error) {
+	var settled int64
+	err := tx.Model(&model.Payment{}).
+		Where("transaction_id = ? AND kind IN ?", transactionID, []string{model.PaymentKindPelunasan, model.PaymentKindReversal}).
+		Select("COALESCE(SUM(amount), 0)").
+		Scan(&settled).Error
+	return settled, err
+}
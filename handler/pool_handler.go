@@ -0,0 +1,53 @@
+package handler
+
+import (
+	"database/sql"
+	"net/http"
+	"rekap-backend/apierror"
+	"rekap-backend/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PoolStatsResult is the usage of one connection pool
+type PoolStatsResult struct {
+	MaxOpenConnections int   `json:"max_open_connections"`
+	OpenConnections    int   `json:"open_connections"`
+	InUse              int   `json:"in_use"`
+	Idle               int   `json:"idle"`
+	WaitCount          int64 `json:"wait_count"`       // Connections that had to be waited for
+	WaitDurationMs     int64 `json:"wait_duration_ms"` // Total time spent waiting
+	MaxIdleClosed      int64 `json:"max_idle_closed"`
+	MaxIdleTimeClosed  int64 `json:"max_idle_time_closed"`
+	MaxLifetimeClosed  int64 `json:"max_lifetime_closed"`
+}
+
+// GetPoolStats returns the connection pool usage of the primary and the read replica. Owner only.
+func GetPoolStats(c *gin.Context) {
+	pools, err := config.PoolStats()
+	if err != nil {
+		apierror.Internal(c, err, "Failed to read pool stats")
+		return
+	}
+
+	data := make(map[string]PoolStatsResult, len(pools))
+	for name, s := range pools {
+		data[name] = newPoolStatsResult(s)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": data})
+}
+
+func newPoolStatsResult(s sql.DBStats) PoolStatsResult {
+	return PoolStatsResult{
+		MaxOpenConnections: s.MaxOpenConnections,
+		OpenConnections:    s.OpenConnections,
+		InUse:              s.InUse,
+		Idle:               s.Idle,
+		WaitCount:          s.WaitCount,
+		WaitDurationMs:     s.WaitDuration.Milliseconds(),
+		MaxIdleClosed:      s.MaxIdleClosed,
+		MaxIdleTimeClosed:  s.MaxIdleTimeClosed,
+		MaxLifetimeClosed:  s.MaxLifetimeClosed,
+	}
+}
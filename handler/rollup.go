@@ -0,0 +1,22 @@
+package handler
+
+import (
+	"rekap-backend/businessday"
+	"rekap-backend/cache"
+	"rekap-backend/model"
+	"rekap-backend/stats"
+
+	"gorm.io/gorm"
+)
+
+// refreshDailyStats brings the daily_branch_stats row of a transaction's branch and business
+// date up to date. Call it inside the database transaction that changed it.
+func refreshDailyStats(tx *gorm.DB, t model.Transaction) error {
+	return stats.Refresh(tx, t.BranchID, businessday.DateOf(t.TanggalMasuk).Format("2006-01-02"))
+}
+
+// invalidateSummaries drops the cached summaries covering a transaction's branch and business
+// date. Call it once the change is committed.
+func invalidateSummaries(t model.Transaction) {
+	cache.Invalidate(t.BranchID, businessday.DateOf(t.TanggalMasuk).Format("2006-01-02"))
+}
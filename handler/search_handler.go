@@ -0,0 +1,129 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"rekap-backend/apierror"
+	"rekap-backend/config"
+	"rekap-backend/model"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Search result limits
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+	minSearchLength    = 2
+)
+
+// Highlight marks a matched part of a field, as rune offsets [start, end)
+type Highlight struct {
+	Field string `json:"field"`
+	Start int    `json:"start"`
+	End   int    `json:"end"`
+}
+
+// SearchHit is a single ranked search candidate
+type SearchHit struct {
+	Transaction model.Transaction `json:"transaction"`
+	Score       float64           `json:"score"`
+	Highlights  []Highlight       `json:"highlights"`
+}
+
+// searchRow is the scan target of the ranked search query
+type searchRow struct {
+	model.Transaction `gorm:"embedded"`
+	Score             float64 `gorm:"column:score"`
+}
+
+// SearchTransactions runs a ranked fuzzy search over nama_pelanggan and no_transaksi.
+// Query params: q (required, min 2 chars). Optional: branch_id (repeatable), start_date, end_date, limit
+func SearchTransactions(c *gin.Context) {
+	errs := apierror.FieldErrors{}
+
+	q := strings.TrimSpace(c.Query("q"))
+	if len([]rune(q)) < minSearchLength {
+		errs["q"] = fmt.Sprintf("must be at least %d characters", minSearchLength)
+	}
+
+	window := parseDateRangeParams(c, errs)
+	branchIDs := parseBranchIDsParam(c, errs)
+
+	limit := defaultSearchLimit
+	if raw := c.Query("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 || n > maxSearchLimit {
+			errs["limit"] = fmt.Sprintf("must be an integer between 1 and %d", maxSearchLimit)
+		}
+		limit = n
+	}
+
+	if len(errs) > 0 {
+		apierror.InvalidQuery(c, errs)
+		return
+	}
+
+	// Word similarity ranks partial names and receipt fragments, ILIKE catches exact substrings
+	// the trigram threshold would miss. Both are served by the pg_trgm GIN indexes.
+	pattern := "%" + escapeLike(q) + "%"
+	query := config.DB.WithContext(c).Model(&model.Transaction{}).
+		Select("transactions.*, GREATEST(word_similarity(?, nama_pelanggan), word_similarity(?, no_transaksi)) AS score", q, q).
+		Where("? <% nama_pelanggan OR ? <% no_transaksi OR nama_pelanggan ILIKE ? OR no_transaksi ILIKE ?", q, q, pattern, pattern)
+
+	if window != nil {
+		query = query.Where("tanggal_masuk >= ? AND tanggal_masuk < ?", window.Start, window.End)
+	}
+	if len(branchIDs) > 0 {
+		query = query.Where("branch_id IN ?", branchIDs)
+	}
+
+	var rows []searchRow
+	result := query.Order("score DESC, tanggal_masuk DESC, id DESC").Limit(limit).Scan(&rows)
+	if result.Error != nil {
+		apierror.Internal(c, result.Error, "Failed to search transactions")
+		return
+	}
+
+	hits := make([]SearchHit, 0, len(rows))
+	for _, row := range rows {
+		highlights := append(
+			highlightMatches("nama_pelanggan", row.NamaPelanggan, q),
+			highlightMatches("no_transaksi", row.NoTransaksi, q)...,
+		)
+		hits = append(hits, SearchHit{
+			Transaction: row.Transaction,
+			Score:       row.Score,
+			Highlights:  highlights,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":   hits,
+		"query":  q,
+		"window": window,
+	})
+}
+
+// highlightMatches finds case-insensitive occurrences of each query term in value
+func highlightMatches(field, value, q string) []Highlight {
+	haystack := []rune(strings.ToLower(value))
+	terms := strings.FieldsFunc(strings.ToLower(q), func(r rune) bool {
+		return unicode.IsSpace(r) || r == '/'
+	})
+
+	var highlights []Highlight
+	for _, term := range terms {
+		needle := []rune(term)
+		for i := 0; i+len(needle) <= len(haystack); i++ {
+			if string(haystack[i:i+len(needle)]) == term {
+				highlights = append(highlights, Highlight{Field: field, Start: i, End: i + len(needle)})
+				i += len(needle) - 1
+			}
+		}
+	}
+	return highlights
+}
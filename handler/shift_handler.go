@@ -0,0 +1,288 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"rekap-backend/apierror"
+	"rekap-backend/audit"
+	"rekap-backend/config"
+	"rekap-backend/model"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// OpenShiftRequest is the body of POST /api/shifts/open
+type OpenShiftRequest struct {
+	BranchID     int   `json:"branch_id" binding:"required,min=1"`
+	OpeningFloat int64 `json:"opening_float" binding:"min=0"` // Whole rupiah in the drawer at the start
+}
+
+// CloseShiftRequest is the body of POST /api/shifts/:id/close
+type CloseShiftRequest struct {
+	CountedCash *int64 `json:"counted_cash" binding:"required,min=0"` // Whole rupiah counted in the drawer
+	Note        string `json:"note"`
+}
+
+// OpenShift starts a cash drawer shift for the current user. A branch can have one open shift.
+func OpenShift(c *gin.Context) {
+	var req OpenShiftRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.BindError(c, err)
+		return
+	}
+
+	shift := model.Shift{
+		BranchID:     req.BranchID,
+		UserID:       c.GetInt("user_id"),
+		OpeningFloat: req.OpeningFloat,
+		OpenedAt:     time.Now(),
+	}
+
+	err := config.DB.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		var open int64
+		if err := tx.Model(&model.Shift{}).Where("branch_id = ? AND closed_at IS NULL", req.BranchID).Count(&open).Error; err != nil {
+			return err
+		}
+		if open > 0 {
+			return errShiftAlreadyOpen
+		}
+		return tx.Create(&shift).Error
+	})
+	// The partial unique index catches the race the count above cannot
+	if errors.Is(err, errShiftAlreadyOpen) || apierror.IsUniqueViolation(err, "idx_shifts_one_open_per_branch") {
+		apierror.Conflict(c, "This branch already has an open shift, close it first")
+		return
+	}
+	if err != nil {
+		apierror.Fail(c, err, "Failed to open shift")
+		return
+	}
+
+	audit.Set(c, audit.Entry{
+		Action:   "shift.open",
+		Entity:   "shift",
+		EntityID: strconv.FormatInt(shift.ID, 10),
+		After:    shift,
+	})
+
+	c.JSON(http.StatusCreated, gin.H{"data": shift})
+}
+
+var errShiftAlreadyOpen = errors.New("branch already has an open shift")
+
+// CloseShift closes a shift and reconciles the drawer: expected cash is the opening float plus
+// every payment taken at the branch while the shift was open. Only the shift's cashier or an
+// owner can close it.
+func CloseShift(c *gin.Context) {
+	id, ok := parsePathID(c, "id")
+	if !ok {
+		return
+	}
+
+	var req CloseShiftRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.BindError(c, err)
+		return
+	}
+
+	var shift, before model.Shift
+	err := config.DB.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		// Lock the shift so it cannot be closed twice
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&shift, id).Error; err != nil {
+			return err
+		}
+		if shift.ClosedAt != nil {
+			return errShiftClosed
+		}
+		if shift.UserID != c.GetInt("user_id") && c.GetString("role") != model.RoleOwner {
+			return errShiftNotYours
+		}
+		before = shift
+
+		closedAt := time.Now()
+		var taken int64
+		err := tx.Model(&model.Payment{}).
+			Where("branch_id = ? AND received_at >= ? AND received_at < ?", shift.BranchID, shift.OpenedAt, closedAt).
+			Select("COALESCE(SUM(amount), 0)").
+			Scan(&taken).Error
+		if err != nil {
+			return err
+		}
+
+		expected := shift.OpeningFloat + taken
+		variance := *req.CountedCash - expected
+		closedBy := c.GetInt("user_id")
+
+		shift.ClosedAt = &closedAt
+		shift.ClosedBy = &closedBy
+		shift.ExpectedCash = &expected
+		shift.CountedCash = req.CountedCash
+		shift.Variance = &variance
+		shift.Note = strings.TrimSpace(req.Note)
+
+		return tx.Save(&shift).Error
+	})
+
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		apierror.NotFound(c, "Shift not found")
+		return
+	case errors.Is(err, errShiftClosed):
+		apierror.Conflict(c, "Shift is already closed")
+		return
+	case errors.Is(err, errShiftNotYours):
+		apierror.Forbidden(c, "Only the shift's cashier or an owner can close it")
+		return
+	case err != nil:
+		apierror.Internal(c, err, "Failed to close shift")
+		return
+	}
+
+	audit.Set(c, audit.Entry{
+		Action:   "shift.close",
+		Entity:   "shift",
+		EntityID: strconv.FormatInt(shift.ID, 10),
+		Before:   before,
+		After:    shift,
+	})
+
+	c.JSON(http.StatusOK, gin.H{"data": shift})
+}
+
+var (
+	errShiftClosed   = errors.New("shift is already closed")
+	errShiftNotYours = errors.New("shift belongs to another cashier")
+)
+
+// GetShifts returns shift history, newest first.
+// Optional query params: branch_id (repeatable), user_id, status (open|closed),
+// start_date, end_date (YYYY-MM-DD, by opened_at), before_id, limit
+func GetShifts(c *gin.Context) {
+	errs := apierror.FieldErrors{}
+	query := config.DB.WithContext(c).Model(&model.Shift{})
+
+	if branchIDs := parseBranchIDsParam(c, errs); len(branchIDs) > 0 {
+		query = query.Where("branch_id IN ?", branchIDs)
+	}
+	if raw := c.Query("user_id"); raw != "" {
+		if userID, err := strconv.Atoi(raw); err != nil {
+			errs["user_id"] = "must be an integer"
+		} else {
+			query = query.Where("user_id = ?", userID)
+		}
+	}
+	switch c.Query("status") {
+	case "":
+	case "open":
+		query = query.Where("closed_at IS NULL")
+	case "closed":
+		query = query.Where("closed_at IS NOT NULL")
+	default:
+		errs["status"] = "must be open or closed"
+	}
+	if window := parseCalendarRangeParams(c, errs); window != nil {
+		query = query.Where("opened_at >= ? AND opened_at < ?", window.Start, window.End)
+	}
+	if raw := c.Query("before_id"); raw != "" {
+		if beforeID, err := strconv.ParseInt(raw, 10, 64); err != nil {
+			errs["before_id"] = "must be an integer"
+		} else {
+			query = query.Where("id < ?", beforeID)
+		}
+	}
+
+	limit := defaultPageSize
+	if raw := c.Query("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 || n > maxPageSize {
+			errs["limit"] = fmt.Sprintf("must be an integer between 1 and %d", maxPageSize)
+		}
+		limit = n
+	}
+
+	if len(errs) > 0 {
+		apierror.InvalidQuery(c, errs)
+		return
+	}
+
+	var shifts []model.Shift
+	if err := query.Order("id DESC").Limit(limit).Find(&shifts).Error; err != nil {
+		apierror.Internal(c, err, "Failed to fetch shifts")
+		return
+	}
+
+	var nextBeforeID *int64
+	if len(shifts) == limit {
+		nextBeforeID = &shifts[len(shifts)-1].ID
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":           shifts,
+		"next_before_id": nextBeforeID,
+	})
+}
+
+// ShiftVarianceResult holds the drawer reconciliation totals of one branch. Amounts are whole rupiah.
+type ShiftVarianceResult struct {
+	BranchID        int   `json:"branch_id"`
+	ClosedShifts    int64 `json:"closed_shifts"`
+	ShortShifts     int64 `json:"short_shifts"` // Shifts with a negative variance
+	OverShifts      int64 `json:"over_shifts"`  // Shifts with a positive variance
+	TotalExpected   int64 `json:"total_expected"`
+	TotalCounted    int64 `json:"total_counted"`
+	NetVariance     int64 `json:"net_variance"`
+	TotalShortage   int64 `json:"total_shortage"` // Sum of negative variances, as a positive amount
+	LargestShortage int64 `json:"largest_shortage"`
+}
+
+// GetShiftVarianceReport aggregates the variance of closed shifts per branch. Owner only.
+// Query params: start_date, end_date (YYYY-MM-DD, by opened_at). Optional: branch_id (repeatable)
+func GetShiftVarianceReport(c *gin.Context) {
+	errs := apierror.FieldErrors{}
+	window := parseCalendarRangeParams(c, errs)
+	if window == nil && len(errs) == 0 {
+		errs["start_date"] = "is required"
+		errs["end_date"] = "is required"
+	}
+	branchIDs := parseBranchIDsParam(c, errs)
+
+	if len(errs) > 0 {
+		apierror.InvalidQuery(c, errs)
+		return
+	}
+
+	query := config.DB.WithContext(c).Model(&model.Shift{}).
+		Where("closed_at IS NOT NULL").
+		Where("opened_at >= ? AND opened_at < ?", window.Start, window.End)
+	if len(branchIDs) > 0 {
+		query = query.Where("branch_id IN ?", branchIDs)
+	}
+
+	var results []ShiftVarianceResult
+	err := query.Select(`
+		branch_id,
+		COUNT(*) as closed_shifts,
+		COUNT(CASE WHEN variance < 0 THEN 1 END) as short_shifts,
+		COUNT(CASE WHEN variance > 0 THEN 1 END) as over_shifts,
+		COALESCE(SUM(expected_cash), 0)::BIGINT as total_expected,
+		COALESCE(SUM(counted_cash), 0)::BIGINT as total_counted,
+		COALESCE(SUM(variance), 0)::BIGINT as net_variance,
+		COALESCE(-SUM(CASE WHEN variance < 0 THEN variance END), 0)::BIGINT as total_shortage,
+		COALESCE(-MIN(CASE WHEN variance < 0 THEN variance END), 0)::BIGINT as largest_shortage
+	`).Group("branch_id").Order("branch_id ASC").Scan(&results).Error
+	if err != nil {
+		apierror.Internal(c, err, "Failed to build variance report")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":   results,
+		"window": window,
+	})
+}
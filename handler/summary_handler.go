@@ -2,8 +2,11 @@ package handler
 
 import (
 	"net/http"
+	"rekap-backend/apierror"
+	"rekap-backend/businessday"
+	"rekap-backend/cache"
 	"rekap-backend/config"
-	"time"
+	"rekap-backend/stats"
 
 	"github.com/gin-gonic/gin"
 )
@@ -12,106 +15,129 @@ import (
 type DailySummaryResult struct {
 	Date              string  `json:"date"`
 	TotalTransactions int64   `json:"total_transactions"`
-	TotalRevenue      float64 `json:"total_revenue"`
+	TotalRevenue      int64   `json:"total_revenue"` // Whole rupiah
 	TotalKg           float64 `json:"total_kg"`
 	TotalPc           int64   `json:"total_pc"`
 	TotalPaid         int64   `json:"total_paid"` // Count of transactions with status_pembayaran = 'lunas'
 }
 
-// GetDailySummary returns the summary for a single day.
-// Query param: date (YYYY-MM-DD), defaults to today. Optional: branch_id
+// GetDailySummary returns the summary for a single business day.
+// Query param: date (YYYY-MM-DD), defaults to today. Optional: branch_id (repeatable), source
+// (rollup or live, see parseSourceParam)
 func GetDailySummary(c *gin.Context) {
-	dateStr := c.DefaultQuery("date", time.Now().Format("2006-01-02"))
+	dateStr := c.DefaultQuery("date", businessday.Today())
 
-	parsed, err := time.Parse("2006-01-02", dateStr)
+	parsed, err := businessday.ParseDate(dateStr)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date format, use: YYYY-MM-DD"})
+		apierror.InvalidQuery(c, apierror.FieldErrors{"date": "invalid format, use: YYYY-MM-DD"})
 		return
 	}
 
-	start := parsed
-	end := parsed.Add(24 * time.Hour)
+	errs := apierror.FieldErrors{}
+	branchIDs := parseBranchIDsParam(c, errs)
+	source := parseSourceParam(c, errs)
+	if len(errs) > 0 {
+		apierror.InvalidQuery(c, errs)
+		return
+	}
 
-	query := config.DB.Table("transactions").
-		Where("tanggal_masuk >= ? AND tanggal_masuk < ?", start, end)
+	cache.SetScope(c, cache.Scope{
+		BranchIDs: branchIDs,
+		Ranges:    []cache.DateRange{{From: dateStr, To: dateStr}},
+	})
 
-	// Optional branch filter
-	if branchID := c.Query("branch_id"); branchID != "" {
-		query = query.Where("branch_id = ?", branchID)
+	rows, err := stats.Query(config.ReadDB.WithContext(c), source, parsed, parsed, branchIDs)
+	if err != nil {
+		apierror.Internal(c, err, "Failed to fetch summary")
+		return
 	}
 
-	var result DailySummaryResult
-	query.Select(`
-		TO_CHAR(tanggal_masuk, 'YYYY-MM-DD') as date,
-		COUNT(*) as total_transactions,
-		COALESCE(SUM(total), 0) as total_revenue,
-		COALESCE(SUM(jumlah_kg), 0) as total_kg,
-		COALESCE(SUM(jumlah_pc), 0) as total_pc,
-		COUNT(CASE WHEN status_pembayaran = 'lunas' THEN 1 END) as total_paid
-	`).Scan(&result)
-
-	result.Date = dateStr
+	result := DailySummaryResult{Date: dateStr}
+	for _, row := range rows {
+		result.TotalTransactions += row.TotalTransactions
+		result.TotalRevenue += row.TotalRevenue
+		result.TotalKg += row.TotalKg
+		result.TotalPc += row.TotalPc
+		result.TotalPaid += row.TotalPaid
+	}
 
-	c.JSON(http.StatusOK, gin.H{"data": result})
+	c.JSON(http.StatusOK, gin.H{
+		"data":   result,
+		"window": businessday.WindowOf(parsed, parsed),
+		"source": source,
+	})
 }
 
 // RangeSummaryResult holds aggregated data grouped by day for a date range
 type RangeSummaryResult struct {
 	Date              string  `json:"date"`
 	TotalTransactions int64   `json:"total_transactions"`
-	TotalRevenue      float64 `json:"total_revenue"`
+	TotalRevenue      int64   `json:"total_revenue"` // Whole rupiah
 	TotalKg           float64 `json:"total_kg"`
 	TotalPc           int64   `json:"total_pc"`
 }
 
-// GetRangeSummary returns a per-day breakdown within a date range.
-// Query params: start_date, end_date (YYYY-MM-DD). Optional: branch_id
+// GetRangeSummary returns a per-business-day breakdown within a date range.
+// Query params: start_date, end_date (YYYY-MM-DD). Optional: branch_id (repeatable), source
 func GetRangeSummary(c *gin.Context) {
 	startStr := c.Query("start_date")
 	endStr := c.Query("end_date")
 
 	if startStr == "" || endStr == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "start_date and end_date are required"})
+		apierror.InvalidQuery(c, apierror.FieldErrors{"start_date": "is required", "end_date": "is required"})
 		return
 	}
 
-	startDate, err := time.Parse("2006-01-02", startStr)
+	startDate, err := businessday.ParseDate(startStr)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid start_date format, use: YYYY-MM-DD"})
+		apierror.InvalidQuery(c, apierror.FieldErrors{"start_date": "invalid format, use: YYYY-MM-DD"})
 		return
 	}
 
-	endDate, err := time.Parse("2006-01-02", endStr)
+	endDate, err := businessday.ParseDate(endStr)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid end_date format, use: YYYY-MM-DD"})
+		apierror.InvalidQuery(c, apierror.FieldErrors{"end_date": "invalid format, use: YYYY-MM-DD"})
 		return
 	}
 
-	// Make end_date inclusive by adding 1 day
-	endDate = endDate.Add(24 * time.Hour)
+	errs := apierror.FieldErrors{}
+	branchIDs := parseBranchIDsParam(c, errs)
+	source := parseSourceParam(c, errs)
+	if len(errs) > 0 {
+		apierror.InvalidQuery(c, errs)
+		return
+	}
 
-	query := config.DB.Table("transactions").
-		Where("tanggal_masuk >= ? AND tanggal_masuk < ?", startDate, endDate)
+	cache.SetScope(c, cache.Scope{
+		BranchIDs: branchIDs,
+		Ranges:    []cache.DateRange{{From: startStr, To: endStr}},
+	})
 
-	// Optional branch filter
-	if branchID := c.Query("branch_id"); branchID != "" {
-		query = query.Where("branch_id = ?", branchID)
+	rows, err := stats.Query(config.ReadDB.WithContext(c), source, startDate, endDate, branchIDs)
+	if err != nil {
+		apierror.Internal(c, err, "Failed to fetch summary")
+		return
 	}
 
-	var results []RangeSummaryResult
-	query.Select(`
-		TO_CHAR(tanggal_masuk, 'YYYY-MM-DD') as date,
-		COUNT(*) as total_transactions,
-		COALESCE(SUM(total), 0) as total_revenue,
-		COALESCE(SUM(jumlah_kg), 0) as total_kg,
-		COALESCE(SUM(jumlah_pc), 0) as total_pc
-	`).Group("TO_CHAR(tanggal_masuk, 'YYYY-MM-DD')").
-		Order("date ASC").
-		Scan(&results)
+	// Rows come per branch and date, ordered by date; fold the branches of each date together
+	results := []RangeSummaryResult{}
+	for _, row := range rows {
+		if len(results) == 0 || results[len(results)-1].Date != row.Date {
+			results = append(results, RangeSummaryResult{Date: row.Date})
+		}
+		day := &results[len(results)-1]
+		day.TotalTransactions += row.TotalTransactions
+		day.TotalRevenue += row.TotalRevenue
+		day.TotalKg += row.TotalKg
+		day.TotalPc += row.TotalPc
+	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"data":       results,
 		"start_date": startStr,
 		"end_date":   endStr,
+		// end_date is inclusive, the window runs until the cutoff after it
+		"window": businessday.WindowOf(startDate, endDate),
+		"source": source,
 	})
 }
@@ -0,0 +1,237 @@
+package handler
+
+import (
+	"rekap-backend/apierror"
+	"rekap-backend/businessday"
+	"rekap-backend/stats"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// transactionSortColumns whitelists the columns GET /api/transactions can be sorted by
+var transactionSortColumns = map[string]string{
+	"tanggal_masuk":  "tanggal_masuk",
+	"no_transaksi":   "no_transaksi",
+	"nama_pelanggan": "nama_pelanggan",
+	"total":          "total",
+	"jumlah_kg":      "jumlah_kg",
+	"created_at":     "created_at",
+}
+
+// paymentStatuses lists the accepted values of status_pembayaran
+var paymentStatuses = map[string]bool{
+	"lunas":       true,
+	"belum lunas": true,
+}
+
+// TransactionFilter holds the validated filter and sort parameters of GET /api/transactions
+type TransactionFilter struct {
+	Window           *businessday.Window
+	BranchIDs        []int
+	Status           string
+	StatusPembayaran string
+	MinTotal         *int64
+	MaxTotal         *int64
+	Search           string
+	Sort             string
+	Order            string
+}
+
+// parseTransactionFilter reads and validates the filter query params.
+// Query params: date or start_date/end_date (YYYY-MM-DD, business dates), branch_id (repeatable or
+// comma separated), status, status_pembayaran, min_total, max_total, q (customer name),
+// sort (default tanggal_masuk), order (default desc)
+func parseTransactionFilter(c *gin.Context) (TransactionFilter, apierror.FieldErrors) {
+	var f TransactionFilter
+	errs := apierror.FieldErrors{}
+
+	// Business date filter: either a single date or a range
+	date := c.Query("date")
+	if date != "" && (c.Query("start_date") != "" || c.Query("end_date") != "") {
+		errs["date"] = "cannot be combined with start_date/end_date"
+	} else if date != "" {
+		if parsed, err := businessday.ParseDate(date); err != nil {
+			errs["date"] = "invalid format, use: YYYY-MM-DD"
+		} else {
+			w := businessday.WindowOf(parsed, parsed)
+			f.Window = &w
+		}
+	} else {
+		f.Window = parseDateRangeParams(c, errs)
+	}
+
+	f.BranchIDs = parseBranchIDsParam(c, errs)
+
+	f.Status = strings.TrimSpace(c.Query("status"))
+
+	if status := c.Query("status_pembayaran"); status != "" {
+		if !paymentStatuses[status] {
+			errs["status_pembayaran"] = "must be one of: lunas, belum lunas"
+		}
+		f.StatusPembayaran = status
+	}
+
+	f.MinTotal = parseAmountParam(c, "min_total", errs)
+	f.MaxTotal = parseAmountParam(c, "max_total", errs)
+	if f.MinTotal != nil && f.MaxTotal != nil && *f.MaxTotal < *f.MinTotal {
+		errs["max_total"] = "must not be less than min_total"
+	}
+
+	f.Search = strings.TrimSpace(c.Query("q"))
+
+	f.Sort = c.DefaultQuery("sort", "tanggal_masuk")
+	if _, ok := transactionSortColumns[f.Sort]; !ok {
+		errs["sort"] = "must be one of: tanggal_masuk, no_transaksi, nama_pelanggan, total, jumlah_kg, created_at"
+	}
+
+	f.Order = strings.ToLower(c.DefaultQuery("order", "desc"))
+	if f.Order != "asc" && f.Order != "desc" {
+		errs["order"] = "must be asc or desc"
+	}
+
+	return f, errs
+}
+
+// parseDateRangeParams parses optional start_date/end_date (YYYY-MM-DD, inclusive business dates).
+// Returns nil when neither is set.
+func parseDateRangeParams(c *gin.Context, errs apierror.FieldErrors) *businessday.Window {
+	startDate, endDate, ok := parseDates(c, errs)
+	if !ok {
+		return nil
+	}
+
+	w := businessday.WindowOf(startDate, endDate)
+	return &w
+}
+
+// parseCalendarRangeParams is parseDateRangeParams for timestamps that are not assigned to
+// business days (audit entries, shifts): the window runs from midnight to midnight.
+func parseCalendarRangeParams(c *gin.Context, errs apierror.FieldErrors) *businessday.Window {
+	startDate, endDate, ok := parseDates(c, errs)
+	if !ok {
+		return nil
+	}
+
+	return &businessday.Window{Start: startDate, End: endDate.AddDate(0, 0, 1)}
+}
+
+// parseDates validates the start_date/end_date pair, ok is false when unset or invalid
+func parseDates(c *gin.Context, errs apierror.FieldErrors) (startDate, endDate time.Time, ok bool) {
+	startStr := c.Query("start_date")
+	endStr := c.Query("end_date")
+	if startStr == "" && endStr == "" {
+		return startDate, endDate, false
+	}
+
+	startDate, startErr := businessday.ParseDate(startStr)
+	endDate, endErr := businessday.ParseDate(endStr)
+	switch {
+	case startStr == "":
+		errs["start_date"] = "is required when end_date is set"
+	case startErr != nil:
+		errs["start_date"] = "invalid format, use: YYYY-MM-DD"
+	}
+	switch {
+	case endStr == "":
+		errs["end_date"] = "is required when start_date is set"
+	case endErr != nil:
+		errs["end_date"] = "invalid format, use: YYYY-MM-DD"
+	}
+	if startErr != nil || endErr != nil {
+		return startDate, endDate, false
+	}
+	if endDate.Before(startDate) {
+		errs["end_date"] = "must not be before start_date"
+		return startDate, endDate, false
+	}
+
+	return startDate, endDate, true
+}
+
+// parseBranchIDsParam parses ?branch_id=1&branch_id=2 or ?branch_id=1,2
+func parseBranchIDsParam(c *gin.Context, errs apierror.FieldErrors) []int {
+	var ids []int
+	for _, raw := range c.QueryArray("branch_id") {
+		for _, part := range strings.Split(raw, ",") {
+			id, err := strconv.Atoi(strings.TrimSpace(part))
+			if err != nil || id < 1 {
+				errs["branch_id"] = "must be a list of positive integers"
+				return nil
+			}
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// parsePathID parses a positive integer path param, responding 400 when it is not one
+func parsePathID(c *gin.Context, name string) (int, bool) {
+	id, err := strconv.Atoi(c.Param(name))
+	if err != nil || id < 1 {
+		apierror.Invalid(c, "Invalid path parameter", apierror.FieldErrors{name: "must be a positive integer"})
+		return 0, false
+	}
+	return id, true
+}
+
+// parseSourceParam parses the optional source param of aggregate endpoints: rollup (default,
+// the daily_branch_stats table) or live (aggregated from transactions on the fly)
+func parseSourceParam(c *gin.Context, errs apierror.FieldErrors) stats.Source {
+	switch source := stats.Source(c.DefaultQuery("source", string(stats.SourceRollup))); source {
+	case stats.SourceRollup, stats.SourceLive:
+		return source
+	default:
+		errs["source"] = "must be one of: rollup, live"
+		return stats.SourceRollup
+	}
+}
+
+// parseAmountParam parses an optional non-negative whole-rupiah amount query param
+func parseAmountParam(c *gin.Context, name string, errs apierror.FieldErrors) *int64 {
+	raw := c.Query(name)
+	if raw == "" {
+		return nil
+	}
+
+	value, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil || value < 0 {
+		errs[name] = "must be a non-negative whole number of rupiah"
+		return nil
+	}
+	return &value
+}
+
+// apply adds the filter conditions to a transactions query
+func (f TransactionFilter) apply(query *gorm.DB) *gorm.DB {
+	if f.Window != nil {
+		query = query.Where("tanggal_masuk >= ? AND tanggal_masuk < ?", f.Window.Start, f.Window.End)
+	}
+	if len(f.BranchIDs) > 0 {
+		query = query.Where("branch_id IN ?", f.BranchIDs)
+	}
+	if f.Status != "" {
+		query = query.Where("status = ?", f.Status)
+	}
+	if f.StatusPembayaran != "" {
+		query = query.Where("status_pembayaran = ?", f.StatusPembayaran)
+	}
+	if f.MinTotal != nil {
+		query = query.Where("total >= ?", *f.MinTotal)
+	}
+	if f.MaxTotal != nil {
+		query = query.Where("total <= ?", *f.MaxTotal)
+	}
+	if f.Search != "" {
+		query = query.Where("nama_pelanggan ILIKE ?", "%"+escapeLike(f.Search)+"%")
+	}
+	return query
+}
+
+// escapeLike escapes the LIKE wildcards in user input
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
@@ -1,123 +1,406 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
+	"rekap-backend/apierror"
+	"rekap-backend/audit"
+	"rekap-backend/businessday"
 	"rekap-backend/config"
+	"rekap-backend/logging"
+	"rekap-backend/metrics"
 	"rekap-backend/model"
+	"rekap-backend/trxno"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
-// GetTransactions returns a paginated list of transactions with optional filters.
-// Query params: date (YYYY-MM-DD), branch_id, status, page, limit
+// GetTransactions returns a cursor-paginated list of transactions with optional filters.
+// Query params: see parseTransactionFilter and parsePageRequest
 func GetTransactions(c *gin.Context) {
-	var transactions []model.Transaction
+	filter, fieldErrs := parseTransactionFilter(c)
+	pageReq := parsePageRequest(c, fieldErrs)
+
+	if len(fieldErrs) > 0 {
+		apierror.InvalidQuery(c, fieldErrs)
+		return
+	}
+
+	query := filter.apply(config.DB.WithContext(c).Model(&model.Transaction{}))
+
+	transactions, page, err := paginateTransactions(query, filter.Sort, filter.Order, pageReq)
+	if errors.Is(err, errCursorMismatch) {
+		apierror.InvalidQuery(c, apierror.FieldErrors{"cursor": err.Error()})
+		return
+	}
+	if err != nil {
+		apierror.Internal(c, err, "Failed to fetch transactions")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":       transactions,
+		"pagination": page,
+		"window":     filter.Window,
+	})
+}
 
-	query := config.DB.Model(&model.Transaction{})
+// GetTransactionByTrxID finds a transaction by its receipt number.
+// trx_id is either the full number (/transactions/trx/TRX/03/260116/01444, or TRX/260116/01444
+// for imported receipts) or just the sequence (/transactions/trx/01444). A bare sequence repeats
+// every day and in every branch, so pass ?date=YYYY-MM-DD and branch_id to pin it down; without
+// them every match is returned as a candidate. Legacy numbers do not name the branch either.
+// Optional: branch_id
+func GetTransactionByTrxID(c *gin.Context) {
+	trxID := strings.TrimPrefix(c.Param("trx_id"), "/")
 
-	// Filter by date
-	if date := c.Query("date"); date != "" {
-		parsed, err := time.Parse("2006-01-02", date)
-		if err == nil {
-			start := parsed
-			end := parsed.Add(24 * time.Hour)
-			query = query.Where("tanggal_masuk >= ? AND tanggal_masuk < ?", start, end)
+	query := config.DB.WithContext(c).Model(&model.Transaction{})
+	errs := apierror.FieldErrors{}
+	if branchIDs := parseBranchIDsParam(c, errs); len(branchIDs) > 0 {
+		query = query.Where("branch_id IN ?", branchIDs)
+	}
+	if len(errs) > 0 {
+		apierror.InvalidQuery(c, errs)
+		return
+	}
+
+	// Numbers are matched part by part, stored receipts are not always zero-padded the same way
+	if number, err := trxno.Parse(trxID); err == nil {
+		cond, args := number.Where()
+		query = query.Where(cond, args...)
+	} else {
+		seq, err := trxno.ParseSequence(trxID)
+		if err != nil {
+			apierror.Invalid(c, "Invalid path parameter", apierror.FieldErrors{"trx_id": "must be TRX/BB/YYMMDD/NNNNN or a sequence number"})
+			return
+		}
+
+		if dateStr := c.Query("date"); dateStr != "" {
+			date, err := businessday.ParseDate(dateStr)
+			if err != nil {
+				apierror.InvalidQuery(c, apierror.FieldErrors{"date": "invalid format, use: YYYY-MM-DD"})
+				return
+			}
+			// The number of any branch, or the legacy number without one
+			cond, args := trxno.WhereAnyBranch(date, seq)
+			query = query.Where(cond, args...)
+		} else {
+			// The sequence is the last part, stored with or without zero padding
+			query = query.Where(
+				"no_transaksi LIKE ? AND LTRIM(REGEXP_REPLACE(no_transaksi, '^.*/', ''), '0') = ?",
+				trxno.Prefix+"/%/%", strconv.Itoa(seq),
+			)
 		}
 	}
 
-	// Filter by branch
-	if branchID := c.Query("branch_id"); branchID != "" {
-		query = query.Where("branch_id = ?", branchID)
+	var transactions []model.Transaction
+	result := query.Order("tanggal_masuk DESC, id DESC").Limit(maxPageSize).Find(&transactions)
+	if result.Error != nil {
+		apierror.Internal(c, result.Error, "Failed to fetch transaction")
+		return
 	}
 
-	// Filter by status
-	if status := c.Query("status"); status != "" {
-		query = query.Where("status = ?", status)
+	switch len(transactions) {
+	case 0:
+		apierror.NotFound(c, "Transaction not found")
+	case 1:
+		c.JSON(http.StatusOK, gin.H{"data": transactions[0]})
+	default:
+		c.JSON(http.StatusConflict, struct {
+			apierror.Response
+			Candidates []model.Transaction `json:"candidates"`
+		}{
+			Response:   apierror.New(c, apierror.CodeConflict, "Several transactions match, pass the full number, date or branch_id", nil),
+			Candidates: transactions,
+		})
 	}
+}
 
-	// Pagination
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
-	if page < 1 {
-		page = 1
+// CreateTransactionRequest is the body of POST /api/transactions. Amounts are whole rupiah.
+type CreateTransactionRequest struct {
+	BranchID         int     `json:"branch_id" binding:"required,min=1"`
+	NamaPelanggan    string  `json:"nama_pelanggan" binding:"required"`
+	Status           string  `json:"status" binding:"required"`
+	DP               int64   `json:"dp" binding:"min=0"`
+	Subtotal         int64   `json:"subtotal" binding:"min=0"`
+	BiayaAntarJemput int64   `json:"biaya_antar_jemput" binding:"min=0"`
+	Diskon           int64   `json:"diskon" binding:"min=0"`
+	DiskonPoin       int64   `json:"diskon_poin" binding:"min=0"`
+	JumlahKg         float64 `json:"jumlah_kg" binding:"min=0"`
+	JumlahPc         int     `json:"jumlah_pc" binding:"min=0"`
+}
+
+// CreateTransaction records a new transaction with a server-generated receipt number.
+// The total is computed as subtotal + biaya_antar_jemput - diskon - diskon_poin.
+func CreateTransaction(c *gin.Context) {
+	var req CreateTransactionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.BindError(c, err)
+		return
 	}
-	offset := (page - 1) * limit
 
-	// Count total records
-	var total int64
-	query.Count(&total)
+	total := req.Subtotal + req.BiayaAntarJemput - req.Diskon - req.DiskonPoin
+	if total < 0 {
+		apierror.Invalid(c, "Discounts must not exceed subtotal plus delivery fee", apierror.FieldErrors{
+			"diskon": "must not exceed subtotal plus biaya_antar_jemput, together with diskon_poin",
+		})
+		return
+	}
+	if req.DP > total {
+		apierror.Invalid(c, "dp must not exceed the total", apierror.FieldErrors{"dp": "must not exceed the total"})
+		return
+	}
 
-	// Fetch records — latest date first, earliest time within the same day first
-	result := query.Order("DATE(tanggal_masuk) DESC, tanggal_masuk ASC").Limit(limit).Offset(offset).Find(&transactions)
-	if result.Error != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch transactions"})
+	statusPembayaran := "belum lunas"
+	if req.DP == total {
+		statusPembayaran = "lunas"
+	}
+
+	now := time.Now()
+	transaction := model.Transaction{
+		BranchID:         req.BranchID,
+		TanggalMasuk:     now,
+		NamaPelanggan:    strings.TrimSpace(req.NamaPelanggan),
+		Status:           req.Status,
+		StatusPembayaran: statusPembayaran,
+		DP:               req.DP,
+		Subtotal:         req.Subtotal,
+		BiayaAntarJemput: req.BiayaAntarJemput,
+		Diskon:           req.Diskon,
+		DiskonPoin:       req.DiskonPoin,
+		Total:            total,
+		JumlahKg:         req.JumlahKg,
+		JumlahPc:         req.JumlahPc,
+	}
+
+	// Reserve the receipt number and insert the row atomically
+	err := config.DB.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		number, err := trxno.Next(tx, req.BranchID, businessday.DateOf(now))
+		if err != nil {
+			return err
+		}
+		transaction.NoTransaksi = number.String()
+		if err := tx.Create(&transaction).Error; err != nil {
+			return err
+		}
+		if err := refreshDailyStats(tx, transaction); err != nil {
+			return err
+		}
+
+		// The down payment is taken at the counter when the order comes in
+		if transaction.DP > 0 {
+			return recordPayment(tx, transaction, transaction.DP, model.PaymentKindDP, c.GetInt("user_id"))
+		}
+		return nil
+	})
+	if err != nil {
+		apierror.Fail(c, err, "Failed to create transaction")
 		return
 	}
+	invalidateSummaries(transaction)
+	logging.SetBranch(c, transaction.BranchID)
+	metrics.TransactionCreated(transaction.BranchID)
 
-	c.JSON(http.StatusOK, gin.H{
-		"data":  transactions,
-		"total": total,
-		"page":  page,
-		"limit": limit,
+	audit.Set(c, audit.Entry{
+		Action:   "transaction.create",
+		Entity:   "transaction",
+		EntityID: strconv.Itoa(transaction.ID),
+		After:    transaction,
 	})
+
+	c.JSON(http.StatusCreated, gin.H{"data": transaction})
 }
 
-// GetTransactionByTrxID finds a transaction by the trailing sequence number of no_transaksi.
-// Example: trx_id=01444 will match no_transaksi = 'TRX/260116/01444'
-func GetTransactionByTrxID(c *gin.Context) {
-	trxID := c.Param("trx_id")
+// GetTransactionByBranchID returns a branch's transactions, newest first, cursor-paginated.
+// Query params: see parsePageRequest
+func GetTransactionByBranchID(c *gin.Context) {
+	branchID, ok := parsePathID(c, "branch_id")
+	if !ok {
+		return
+	}
 
-	var transaction model.Transaction
-	result := config.DB.Where("no_transaksi LIKE ?", "%/"+trxID).First(&transaction)
-	if result.Error != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Transaction not found"})
+	fieldErrs := apierror.FieldErrors{}
+	pageReq := parsePageRequest(c, fieldErrs)
+	if len(fieldErrs) > 0 {
+		apierror.InvalidQuery(c, fieldErrs)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"data": transaction})
+	query := config.DB.WithContext(c).Model(&model.Transaction{}).Where("branch_id = ?", branchID)
+
+	transactions, page, err := paginateTransactions(query, "tanggal_masuk", "desc", pageReq)
+	if errors.Is(err, errCursorMismatch) {
+		apierror.InvalidQuery(c, apierror.FieldErrors{"cursor": err.Error()})
+		return
+	}
+	if err != nil {
+		apierror.Internal(c, err, "Failed to fetch transactions")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":       transactions,
+		"pagination": page,
+	})
 }
 
-func GetTransactionByBranchID(c *gin.Context) {
-	branchID := c.Param("branch_id")
+// TogglePaymentStatus toggles status_pembayaran between 'lunas' and 'belum lunas'.
+// Marking a transaction 'lunas' settles the outstanding balance into pelunasan and records the
+// payment; switching back reverses what was settled at the counter.
+func TogglePaymentStatus(c *gin.Context) {
+	id, ok := parsePathID(c, "id")
+	if !ok {
+		return
+	}
 
-	var transactions []model.Transaction
-	result := config.DB.Where("branch_id = ?", branchID).Order("DATE(tanggal_masuk) DESC, tanggal_masuk ASC").Find(&transactions)
-	if result.Error != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Transactions not found"})
+	userID := c.GetInt("user_id")
+
+	var transaction, before model.Transaction
+	var newStatus string
+	err := config.DB.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		// Lock the transaction so concurrent toggles see each other's status and payments
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&transaction, id).Error; err != nil {
+			return err
+		}
+		before = transaction
+
+		newStatus = "lunas"
+		if transaction.StatusPembayaran == "lunas" {
+			newStatus = "belum lunas"
+		}
+
+		pelunasan := transaction.Pelunasan
+
+		if newStatus == "lunas" {
+			if outstanding := transaction.Total - transaction.DP - transaction.Pelunasan; outstanding > 0 {
+				if err := recordPayment(tx, transaction, outstanding, model.PaymentKindPelunasan, userID); err != nil {
+					return err
+				}
+				pelunasan += outstanding
+			}
+		} else {
+			settled, err := settledThroughLedger(tx, transaction.ID)
+			if err != nil {
+				return err
+			}
+			if settled > 0 {
+				if err := recordPayment(tx, transaction, -settled, model.PaymentKindReversal, userID); err != nil {
+					return err
+				}
+				pelunasan -= settled
+			}
+		}
+
+		err := tx.Model(&transaction).Updates(map[string]any{
+			"status_pembayaran": newStatus,
+			"pelunasan":         pelunasan,
+		}).Error
+		if err != nil {
+			return err
+		}
+		return refreshDailyStats(tx, transaction)
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		apierror.NotFound(c, "Transaction not found")
+		return
+	} else if err != nil {
+		apierror.Internal(c, err, "Failed to update payment status")
 		return
 	}
+	invalidateSummaries(transaction)
+	logging.SetBranch(c, transaction.BranchID)
+	metrics.PaymentToggled(transaction.BranchID, newStatus)
 
-	c.JSON(http.StatusOK, gin.H{"data": transactions})
+	audit.Set(c, audit.Entry{
+		Action:   "transaction.toggle_payment",
+		Entity:   "transaction",
+		EntityID: strconv.Itoa(transaction.ID),
+		Before:   before,
+		After:    transaction,
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":    transaction,
+		"message": "Payment status updated to: " + newStatus,
+	})
 }
 
-// TogglePaymentStatus toggles status_pembayaran between 'lunas' and 'belum lunas'
-func TogglePaymentStatus(c *gin.Context) {
-	id := c.Param("id")
+// DeleteTransactionRequest is the optional body of DELETE /api/transactions/:id
+type DeleteTransactionRequest struct {
+	Reason string `json:"reason"`
+}
+
+// DeleteTransaction moves a transaction to the trash. A reason is required for paid transactions.
+func DeleteTransaction(c *gin.Context) {
+	id, ok := parsePathID(c, "id")
+	if !ok {
+		return
+	}
+
+	var req DeleteTransactionRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			apierror.BindError(c, err)
+			return
+		}
+	}
+	req.Reason = strings.TrimSpace(req.Reason)
 
-	// Find the transaction first
 	var transaction model.Transaction
-	if err := config.DB.First(&transaction, id).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Transaction not found"})
+	if err := config.DB.WithContext(c).First(&transaction, id).Error; errors.Is(err, gorm.ErrRecordNotFound) {
+		apierror.NotFound(c, "Transaction not found")
+		return
+	} else if err != nil {
+		apierror.Internal(c, err, "Failed to fetch transaction")
+		return
+	}
+
+	if transaction.StatusPembayaran == "lunas" && req.Reason == "" {
+		apierror.Invalid(c, "A reason is required to delete a paid transaction",
+			apierror.FieldErrors{"reason": "is required for paid transactions"})
 		return
 	}
 
-	// Toggle the status
-	newStatus := "lunas"
-	if transaction.StatusPembayaran == "lunas" {
-		newStatus = "belum lunas"
+	before := transaction
+
+	userID := c.GetInt("user_id")
+	updates := map[string]any{
+		"deleted_at": time.Now(),
+		"deleted_by": userID,
+	}
+	if req.Reason != "" {
+		updates["delete_reason"] = req.Reason
 	}
 
-	// Update only the status_pembayaran column
-	if err := config.DB.Model(&transaction).Update("status_pembayaran", newStatus).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update payment status"})
+	err := config.DB.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&transaction).Updates(updates).Error; err != nil {
+			return err
+		}
+		return refreshDailyStats(tx, transaction)
+	})
+	if err != nil {
+		apierror.Internal(c, err, "Failed to delete transaction")
 		return
 	}
+	invalidateSummaries(transaction)
+	logging.SetBranch(c, transaction.BranchID)
+
+	audit.Set(c, audit.Entry{
+		Action:   "transaction.delete",
+		Entity:   "transaction",
+		EntityID: strconv.Itoa(transaction.ID),
+		Before:   before,
+		After:    transaction,
+		Metadata: map[string]any{"reason": req.Reason},
+	})
 
 	c.JSON(http.StatusOK, gin.H{
 		"data":    transaction,
-		"message": "Payment status updated to: " + newStatus,
+		"message": "Transaction moved to trash",
 	})
 }
-
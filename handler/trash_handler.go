@@ -0,0 +1,140 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"rekap-backend/apierror"
+	"rekap-backend/audit"
+	"rekap-backend/config"
+	"rekap-backend/logging"
+	"rekap-backend/model"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// GetTrash returns soft-deleted transactions, cursor-paginated. Owner only.
+// Optional query params: branch_id, plus see parsePageRequest
+func GetTrash(c *gin.Context) {
+	fieldErrs := apierror.FieldErrors{}
+	branchIDs := parseBranchIDsParam(c, fieldErrs)
+	pageReq := parsePageRequest(c, fieldErrs)
+
+	if len(fieldErrs) > 0 {
+		apierror.InvalidQuery(c, fieldErrs)
+		return
+	}
+
+	query := config.DB.WithContext(c).Unscoped().Model(&model.Transaction{}).Where("deleted_at IS NOT NULL")
+	if len(branchIDs) > 0 {
+		query = query.Where("branch_id IN ?", branchIDs)
+	}
+
+	transactions, page, err := paginateTransactions(query, "tanggal_masuk", "desc", pageReq)
+	if errors.Is(err, errCursorMismatch) {
+		apierror.InvalidQuery(c, apierror.FieldErrors{"cursor": err.Error()})
+		return
+	}
+	if err != nil {
+		apierror.Internal(c, err, "Failed to fetch trash")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":       transactions,
+		"pagination": page,
+	})
+}
+
+// findTrashed loads a soft-deleted transaction, responding 404 when it is not in the trash
+func findTrashed(c *gin.Context) (model.Transaction, bool) {
+	var transaction model.Transaction
+	id, ok := parsePathID(c, "id")
+	if !ok {
+		return transaction, false
+	}
+
+	err := config.DB.WithContext(c).Unscoped().Where("deleted_at IS NOT NULL").First(&transaction, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		apierror.NotFound(c, "Transaction not found in trash")
+		return transaction, false
+	}
+	if err != nil {
+		apierror.Internal(c, err, "Failed to fetch transaction")
+		return transaction, false
+	}
+	return transaction, true
+}
+
+// RestoreTransaction takes a transaction out of the trash. Owner only.
+func RestoreTransaction(c *gin.Context) {
+	transaction, ok := findTrashed(c)
+	if !ok {
+		return
+	}
+
+	before := transaction
+
+	err := config.DB.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		err := tx.Unscoped().Model(&transaction).Updates(map[string]any{
+			"deleted_at":    nil,
+			"deleted_by":    nil,
+			"delete_reason": nil,
+		}).Error
+		if err != nil {
+			return err
+		}
+		return refreshDailyStats(tx, transaction)
+	})
+	if err != nil {
+		apierror.Internal(c, err, "Failed to restore transaction")
+		return
+	}
+	invalidateSummaries(transaction)
+	logging.SetBranch(c, transaction.BranchID)
+
+	audit.Set(c, audit.Entry{
+		Action:   "transaction.restore",
+		Entity:   "transaction",
+		EntityID: strconv.Itoa(transaction.ID),
+		Before:   before,
+		After:    transaction,
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":    transaction,
+		"message": "Transaction restored",
+	})
+}
+
+// PurgeTransaction permanently deletes a transaction that is already in the trash. Owner only.
+func PurgeTransaction(c *gin.Context) {
+	transaction, ok := findTrashed(c)
+	if !ok {
+		return
+	}
+
+	// A trashed transaction no longer counts, the refresh only keeps the rollup tidy
+	err := config.DB.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Delete(&transaction).Error; err != nil {
+			return err
+		}
+		return refreshDailyStats(tx, transaction)
+	})
+	if err != nil {
+		apierror.Internal(c, err, "Failed to purge transaction")
+		return
+	}
+	invalidateSummaries(transaction)
+	logging.SetBranch(c, transaction.BranchID)
+
+	audit.Set(c, audit.Entry{
+		Action:   "transaction.purge",
+		Entity:   "transaction",
+		EntityID: strconv.Itoa(transaction.ID),
+		Before:   transaction,
+	})
+
+	c.JSON(http.StatusOK, gin.H{"message": "Transaction permanently deleted"})
+}
@@ -0,0 +1,72 @@
+package logging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// GormLogger writes GORM's logs through slog: every statement at debug level, statements
+// slower than SlowThreshold as warnings and failed ones as errors. "Record not found" is an
+// expected outcome, not an error.
+type GormLogger struct {
+	SlowThreshold time.Duration
+}
+
+// NewGormLogger returns a GORM logger warning about statements slower than slowThreshold
+func NewGormLogger(slowThreshold time.Duration) *GormLogger {
+	return &GormLogger{SlowThreshold: slowThreshold}
+}
+
+// LogMode is ignored, the slog level decides what is written
+func (l *GormLogger) LogMode(gormlogger.LogLevel) gormlogger.Interface {
+	return l
+}
+
+func (l *GormLogger) Info(ctx context.Context, msg string, args ...any) {
+	logger(ctx).InfoContext(ctx, fmt.Sprintf(msg, args...))
+}
+
+func (l *GormLogger) Warn(ctx context.Context, msg string, args ...any) {
+	logger(ctx).WarnContext(ctx, fmt.Sprintf(msg, args...))
+}
+
+func (l *GormLogger) Error(ctx context.Context, msg string, args ...any) {
+	logger(ctx).ErrorContext(ctx, fmt.Sprintf(msg, args...))
+}
+
+// Trace logs one statement after it ran
+func (l *GormLogger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	elapsed := time.Since(begin)
+	slow := l.SlowThreshold > 0 && elapsed > l.SlowThreshold
+	failed := err != nil && !errors.Is(err, gorm.ErrRecordNotFound)
+
+	log := logger(ctx)
+	if !failed && !slow && !log.Enabled(ctx, slog.LevelDebug) {
+		return
+	}
+
+	sql, rows := fc()
+	attrs := []any{"sql", sql, "rows", rows, "duration_ms", float64(elapsed.Microseconds()) / 1000}
+	switch {
+	case failed:
+		log.ErrorContext(ctx, "query failed", append(attrs, "error", err)...)
+	case slow:
+		log.WarnContext(ctx, "slow query", append(attrs, "threshold_ms", l.SlowThreshold.Milliseconds())...)
+	default:
+		log.DebugContext(ctx, "query", attrs...)
+	}
+}
+
+// logger adds the request ID when the statement ran with a request context
+func logger(ctx context.Context) *slog.Logger {
+	if id := RequestID(ctx); id != "" {
+		return slog.Default().With("request_id", id)
+	}
+	return slog.Default()
+}
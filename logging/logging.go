@@ -0,0 +1,163 @@
+// Package logging sets up structured JSON logging with log/slog.
+//
+// Middleware gives every request an X-Request-ID and writes one access line when it finishes,
+// with the route, status, latency, user, branch and trace ID, plus every error a handler attached with
+// c.Error. For handlers that log themselves, For returns a logger carrying the same request
+// attributes.
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"log/slog"
+	"net/http"
+	"os"
+	"rekap-backend/apierror"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// RequestIDHeader carries the request ID in and out
+const RequestIDHeader = "X-Request-ID"
+
+// contextKey types keep the request context values private to this package
+type contextKey string
+
+const requestIDKey contextKey = "request_id"
+
+// branchKey is where handlers note the branch a request touched, see SetBranch
+const branchKey = "log_branch_id"
+
+// traceKey is where the tracing middleware notes the request's trace ID, see SetTraceID
+const traceKey = "log_trace_id"
+
+// Setup makes a JSON handler at the given level the default logger. The standard log package
+// writes through it too, so log.Printf output becomes JSON at info level.
+func Setup(level slog.Level) {
+	handler := slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: level})
+	slog.SetDefault(slog.New(handler))
+	log.SetFlags(0)
+}
+
+// Fatal logs an error and exits, for startup failures
+func Fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
+}
+
+// RequestID returns the request ID stored in ctx, or ""
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey).(string)
+	return id
+}
+
+// SetBranch records the branch a request worked on, for the access log line
+func SetBranch(c *gin.Context, branchID int) {
+	c.Set(branchKey, branchID)
+}
+
+// SetTraceID records the trace ID of the request's span. The access line is written after the
+// tracing middleware has restored the request context, so it cannot find the span there.
+func SetTraceID(c *gin.Context, traceID string) {
+	c.Set(traceKey, traceID)
+}
+
+// For returns the default logger with the request ID, trace ID, route and user of the request
+func For(c *gin.Context) *slog.Logger {
+	logger := slog.Default().With("request_id", c.GetString("request_id"), "route", c.FullPath())
+	if sc := trace.SpanContextFromContext(c.Request.Context()); sc.IsValid() {
+		logger = logger.With("trace_id", sc.TraceID().String())
+	} else if traceID := c.GetString(traceKey); traceID != "" {
+		logger = logger.With("trace_id", traceID)
+	}
+	if userID, ok := c.Get("user_id"); ok {
+		logger = logger.With("user_id", userID)
+	}
+	return logger
+}
+
+// Middleware assigns the request ID, taken from X-Request-ID when the caller (or a proxy)
+// sent a sane one, and writes the access log line once the request is done
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		id := c.GetHeader(RequestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+		c.Set("request_id", id)
+		c.Header(RequestIDHeader, id)
+		c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), requestIDKey, id))
+
+		c.Next()
+
+		status := c.Writer.Status()
+		attrs := []any{
+			"method", c.Request.Method,
+			"path", c.Request.URL.Path,
+			"status", status,
+			"latency_ms", float64(time.Since(start).Microseconds()) / 1000,
+			"client_ip", c.ClientIP(),
+		}
+		if branchID, ok := Branch(c); ok {
+			attrs = append(attrs, "branch_id", branchID)
+		}
+		if len(c.Errors) > 0 {
+			attrs = append(attrs, "errors", c.Errors.Errors())
+		}
+
+		level := slog.LevelInfo
+		switch {
+		case status >= http.StatusInternalServerError || len(c.Errors) > 0:
+			level = slog.LevelError
+		case status >= http.StatusBadRequest:
+			level = slog.LevelWarn
+		}
+		For(c).Log(c.Request.Context(), level, "request", attrs...)
+	}
+}
+
+// Recovery turns a panic into a logged 500 instead of gin's plain-text stack dump
+func Recovery() gin.HandlerFunc {
+	return gin.CustomRecoveryWithWriter(nil, func(c *gin.Context, recovered any) {
+		For(c).Error("panic", "panic", fmt.Sprint(recovered), "stack", string(debug.Stack()))
+		apierror.Abort(c, http.StatusInternalServerError, apierror.CodeInternal, "Internal server error")
+	})
+}
+
+// Branch returns the branch set by the handler, or the branch_id path or query param
+func Branch(c *gin.Context) (int, bool) {
+	if v, ok := c.Get(branchKey); ok {
+		return v.(int), true
+	}
+	raw := c.Param("branch_id")
+	if raw == "" {
+		raw = c.Query("branch_id")
+	}
+	id, err := strconv.Atoi(raw)
+	return id, err == nil
+}
+
+// validRequestID accepts short printable IDs only, so callers cannot inject into the logs
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	return !strings.ContainsFunc(id, func(r rune) bool { return r < 0x21 || r > 0x7e })
+}
+
+// newRequestID returns 16 random bytes as hex
+func newRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
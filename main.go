@@ -1,20 +1,213 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
 	"os"
+	"os/signal"
+	"rekap-backend/apidocs"
+	"rekap-backend/apierror"
+	"rekap-backend/audit"
+	"rekap-backend/cache"
 	"rekap-backend/config"
 	"rekap-backend/handler"
+	"rekap-backend/logging"
+	"rekap-backend/metrics"
 	"rekap-backend/middleware"
+	"rekap-backend/migration"
+	"rekap-backend/model"
+	"rekap-backend/stats"
+	"rekap-backend/tracing"
+	"strconv"
+	"syscall"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 func main() {
+	// JSON logs from the start, at the configured level once it is known
+	logging.Setup(slog.LevelInfo)
+
+	// Load the configuration (defaults, config file or .env, environment, flags).
+	// Whatever follows the flags is a maintenance subcommand.
+	args, err := config.Load(os.Args[1:])
+	if err != nil {
+		logging.Fatal("Failed to load configuration", err)
+	}
+	logging.Setup(config.App.LogLevel)
+
 	// Connect to database
-	config.ConnectDatabase()
+	if err := config.ConnectDatabase(); err != nil {
+		logging.Fatal("Failed to connect to database", err)
+	}
+
+	// Apply pending schema migrations
+	if err := migration.Run(config.DB); err != nil {
+		logging.Fatal("Failed to run migrations", err)
+	}
+
+	// Build the daily stats rollup on first start, or after the business-day settings changed.
+	// The commands that repair or check the rollup see it as it is.
+	if len(args) == 0 || !managesRollup(args[0]) {
+		if rebuilt, err := stats.EnsureRollup(config.DB); err != nil {
+			logging.Fatal("Failed to build daily stats", err)
+		} else if rebuilt {
+			slog.Info("Rebuilt daily_branch_stats for the current business-day settings")
+		}
+	}
+
+	// Maintenance jobs run as subcommands, e.g. `rekap-backend data-quality`
+	if len(args) > 0 {
+		runCommand(args)
+		return
+	}
+
+	// Cache summary responses in memory, invalidated by every write
+	if config.App.SummaryCacheSize > 0 {
+		var replicaLag time.Duration
+		if config.App.Database.ReplicaDSN != "" {
+			replicaLag = config.App.Database.ReplicaMaxLag
+		}
+		cache.Init(cache.NewLRU(config.App.SummaryCacheSize), config.App.SummaryCacheTTL, replicaLag)
+	}
+
+	if !config.App.IsDevelopment() {
+		gin.SetMode(gin.ReleaseMode)
+	}
+
+	// Export traces for requests and database statements
+	shutdownTracing, err := setupTracing()
+	if err != nil {
+		logging.Fatal("Failed to set up tracing", err)
+	}
+
+	// Initialize Gin. Handlers pass the gin context to GORM, which then sees the request's span.
+	r := gin.New()
+	r.ContextWithFallback = true
+
+	// Request ID and one JSON access line per request; panics become logged 500s
+	r.Use(logging.Middleware(), logging.Recovery())
+
+	// Database timings, pool usage and business gauges for /metrics
+	if err := setupMetrics(); err != nil {
+		logging.Fatal("Failed to set up metrics", err)
+	}
+
+	// Request counts and latency per route
+	r.Use(metrics.Middleware())
+
+	// A span per request, continuing the caller's trace from traceparent
+	r.Use(tracing.Middleware(config.App.Tracing.ServiceName), tracing.RequestAttributes())
+
+	// Browser access for the configured origins
+	if len(config.App.CORS.AllowedOrigins) > 0 {
+		r.Use(middleware.CORSMiddleware(config.App.CORS))
+	}
+
+	// Record every mutating request in the audit log
+	r.Use(audit.Middleware())
+
+	// Routes
+	if err := setupRouter(r); err != nil {
+		logging.Fatal("Failed to set up routes", err)
+	}
+
+	server := &http.Server{
+		Addr:              ":" + strconv.Itoa(config.App.Port),
+		Handler:           r,
+		ReadTimeout:       config.App.Server.ReadTimeout,
+		ReadHeaderTimeout: config.App.Server.ReadHeaderTimeout,
+		WriteTimeout:      config.App.Server.WriteTimeout,
+		IdleTimeout:       config.App.Server.IdleTimeout,
+		ErrorLog:          slog.NewLogLogger(slog.Default().Handler(), slog.LevelWarn),
+	}
+
+	go func() {
+		slog.Info("Server listening", "port", config.App.Port, "env", config.App.Env)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logging.Fatal("Server failed", err)
+		}
+	}()
 
-	// Initialize Gin
-	r := gin.Default()
+	// On SIGTERM (orchestrator) or Ctrl+C, stop accepting connections and let in-flight
+	// requests finish before closing the database
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, syscall.SIGTERM, os.Interrupt)
+	<-stop
+
+	slog.Info("Shutting down, draining in-flight requests")
+	handler.MarkDraining()
+	time.Sleep(config.App.Server.ShutdownDelay)
+
+	ctx, cancel := context.WithTimeout(context.Background(), config.App.Server.ShutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		slog.Warn("Shutdown did not finish cleanly", "error", err)
+	}
+
+	if err := shutdownTracing(ctx); err != nil {
+		slog.Warn("Failed to flush traces", "error", err)
+	}
+	config.CloseDatabase()
+	slog.Info("Server stopped")
+}
+
+// setupMetrics instruments the database pools and registers the business gauges
+func setupMetrics() error {
+	pools := map[string]*gorm.DB{"primary": config.DB}
+	if config.ReadDB != config.DB {
+		pools["replica"] = config.ReadDB
+	}
+	for name, db := range pools {
+		if err := metrics.InstrumentGORM(db); err != nil {
+			return err
+		}
+		if err := metrics.RegisterPool(name, db); err != nil {
+			return err
+		}
+	}
+	return metrics.RegisterBusiness(config.ReadDB)
+}
+
+// setupTracing installs the configured exporter and traces statements on both database pools
+func setupTracing() (func(context.Context) error, error) {
+	cfg := config.App.Tracing
+	shutdown, err := tracing.Setup(context.Background(), cfg.Exporter, cfg.ServiceName, cfg.SampleRatio)
+	if err != nil || cfg.Exporter == tracing.ExporterNone {
+		return shutdown, err
+	}
+	pools := []*gorm.DB{config.DB}
+	if config.ReadDB != config.DB {
+		pools = append(pools, config.ReadDB)
+	}
+	for _, db := range pools {
+		if err := tracing.InstrumentGORM(db); err != nil {
+			return nil, err
+		}
+	}
+	return shutdown, nil
+}
+
+// setupRouter registers every route. main_test.go checks them against the OpenAPI spec.
+func setupRouter(r *gin.Engine) error {
+	// Probes for the orchestrator: liveness, and readiness (database reachable, schema current)
+	r.GET("/healthz", handler.Healthz)
+	r.GET("/readyz", handler.Readyz)
+
+	// Prometheus scrape endpoint, behind METRICS_TOKEN. Without a token it is open in development
+	// and answers 404 elsewhere, as the metrics include revenue per branch.
+	metricsAuth := middleware.BearerTokenMiddleware(config.App.MetricsToken)
+	if config.App.MetricsToken == "" && !config.App.IsDevelopment() {
+		slog.Warn("METRICS_TOKEN is not set, /metrics is disabled")
+		metricsAuth = func(c *gin.Context) {
+			apierror.NotFound(c, "No route for "+c.Request.Method+" "+c.Request.URL.Path)
+		}
+	}
+	r.GET("/metrics", metricsAuth, metrics.Handler())
 
 	// Health check
 	r.GET("/ping", func(c *gin.Context) {
@@ -24,6 +217,20 @@ func main() {
 		})
 	})
 
+	// OpenAPI spec and Swagger UI
+	if err := apidocs.Register(r); err != nil {
+		return err
+	}
+
+	// Unknown routes and methods get the usual error body too
+	r.HandleMethodNotAllowed = true
+	r.NoRoute(func(c *gin.Context) {
+		apierror.NotFound(c, "No route for "+c.Request.Method+" "+c.Request.URL.Path)
+	})
+	r.NoMethod(func(c *gin.Context) {
+		apierror.Abort(c, http.StatusMethodNotAllowed, apierror.CodeMethodNotAllowed, "Method not allowed")
+	})
+
 	// Public routes - no token required
 	authRoutes := r.Group("/api/auth")
 	{
@@ -37,23 +244,52 @@ func main() {
 	{
 		// Transactions
 		api.GET("/transactions", handler.GetTransactions)
-		api.GET("/transactions/trx/:trx_id", handler.GetTransactionByTrxID)
+		api.POST("/transactions", handler.CreateTransaction)
+		api.GET("/transactions/trx/*trx_id", handler.GetTransactionByTrxID)
 		api.GET("/transactions/branch/:branch_id", handler.GetTransactionByBranchID)
 		api.PATCH("/transactions/:id/toggle-payment", handler.TogglePaymentStatus)
+		api.DELETE("/transactions/:id", handler.DeleteTransaction)
+
+		// Trash (owner only)
+		api.GET("/transactions/trash", middleware.RequireRole(model.RoleOwner), handler.GetTrash)
+		api.POST("/transactions/:id/restore", middleware.RequireRole(model.RoleOwner), handler.RestoreTransaction)
+		api.DELETE("/transactions/:id/purge", middleware.RequireRole(model.RoleOwner), handler.PurgeTransaction)
+
+		// Search
+		api.GET("/search", handler.SearchTransactions)
 
 		// Summary
-		api.GET("/summary/daily", handler.GetDailySummary)
-		api.GET("/summary/range", handler.GetRangeSummary)
+		api.GET("/summary/daily", cache.Middleware(), handler.GetDailySummary)
+		api.GET("/summary/range", cache.Middleware(), handler.GetRangeSummary)
+		api.GET("/summary/compare", cache.Middleware(), handler.GetSummaryComparison)
+
+		// Analytics
+		api.GET("/analytics/customers", handler.GetCustomerAnalytics)
+		api.GET("/analytics/heatmap", handler.GetDemandHeatmap)
+		api.GET("/forecast", handler.GetForecast)
+
+		// Anomaly routes
+		api.GET("/anomalies", handler.GetAnomalies)
+		api.POST("/anomalies/detect", middleware.RequireRole(model.RoleOwner), handler.DetectAnomalies)
 
 		// Branches
-		api.GET("/branches", handler.GetBranches)
-	}
+		api.GET("/branches", cache.Middleware(), handler.GetBranches)
 
-	// Get port from environment variable
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
-	}
+		// Cash drawer shifts
+		api.POST("/shifts/open", handler.OpenShift)
+		api.POST("/shifts/:id/close", handler.CloseShift)
+		api.GET("/shifts", handler.GetShifts)
+		api.GET("/shifts/variance-report", middleware.RequireRole(model.RoleOwner), handler.GetShiftVarianceReport)
 
-	r.Run(":" + port)
-}
\ No newline at end of file
+		// Audit log (owner only)
+		api.GET("/audit-logs", middleware.RequireRole(model.RoleOwner), handler.GetAuditLogs)
+
+		// Database connection pool usage (owner only)
+		api.GET("/database/pool-stats", middleware.RequireRole(model.RoleOwner), handler.GetPoolStats)
+
+		// Data quality (owner only)
+		api.GET("/data-quality", middleware.RequireRole(model.RoleOwner), handler.GetDataQualityReport)
+		api.POST("/data-quality/fix", middleware.RequireRole(model.RoleOwner), handler.FixDataQuality)
+	}
+	return nil
+}
@@ -0,0 +1,22 @@
+package main
+
+import (
+	"rekap-backend/apidocs"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestRoutesAreDocumented fails when a route is registered without an operation in
+// apidocs/openapi.yaml, or the spec documents a route that no longer exists
+func TestRoutesAreDocumented(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	if err := setupRouter(r); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := apidocs.CheckRoutes(r.Routes()); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,97 @@
+package metrics
+
+import (
+	"context"
+	"rekap-backend/businessday"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"gorm.io/gorm"
+)
+
+// businessQueryTimeout bounds the queries run on each scrape
+const businessQueryTimeout = 5 * time.Second
+
+// activeBranchWindow is how far back a branch's last order is looked for. Branches without an
+// order in this window drop out of rekap_last_transaction_timestamp_seconds.
+const activeBranchWindow = 30 * 24 * time.Hour
+
+var (
+	revenueTodayDesc = prometheus.NewDesc("rekap_revenue_today_rupiah",
+		"Revenue of the current business date in whole rupiah, per branch.", []string{"branch_id"}, nil)
+	transactionsTodayDesc = prometheus.NewDesc("rekap_transactions_today",
+		"Transactions of the current business date, per branch.", []string{"branch_id"}, nil)
+	lastTransactionDesc = prometheus.NewDesc("rekap_last_transaction_timestamp_seconds",
+		"Unix time of the branch's latest order within the last 30 days.", []string{"branch_id"}, nil)
+)
+
+// businessCollector reads the business gauges from the database on every scrape, so they are
+// right however the data changed (API, imports, other instances)
+type businessCollector struct {
+	db *gorm.DB
+}
+
+// RegisterBusiness exports today's revenue and transactions and each branch's latest order,
+// read from db (the replica is fine)
+func RegisterBusiness(db *gorm.DB) error {
+	return Registry.Register(&businessCollector{db: db})
+}
+
+func (b *businessCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- revenueTodayDesc
+	ch <- transactionsTodayDesc
+	ch <- lastTransactionDesc
+}
+
+func (b *businessCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), businessQueryTimeout)
+	defer cancel()
+	db := b.db.WithContext(ctx)
+
+	var lastOrders []struct {
+		BranchID int
+		LastAt   float64
+	}
+	err := db.Table("transactions").
+		Select("branch_id, EXTRACT(EPOCH FROM MAX(tanggal_masuk))::DOUBLE PRECISION as last_at").
+		Where("deleted_at IS NULL AND tanggal_masuk >= ?", time.Now().Add(-activeBranchWindow)).
+		Group("branch_id").
+		Scan(&lastOrders).Error
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(lastTransactionDesc, err)
+		return
+	}
+
+	var today []struct {
+		BranchID          int
+		TotalTransactions int64
+		TotalRevenue      int64
+	}
+	err = db.Table("daily_branch_stats").
+		Select("branch_id, total_transactions, total_revenue").
+		Where("business_date = ?", businessday.DateOf(time.Now()).Format("2006-01-02")).
+		Scan(&today).Error
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(revenueTodayDesc, err)
+		return
+	}
+
+	// Active branches without an order today report zero rather than nothing
+	revenue := map[int]int64{}
+	transactions := map[int]int64{}
+	for _, row := range lastOrders {
+		revenue[row.BranchID] = 0
+		transactions[row.BranchID] = 0
+		ch <- prometheus.MustNewConstMetric(lastTransactionDesc, prometheus.GaugeValue, row.LastAt, strconv.Itoa(row.BranchID))
+	}
+	for _, row := range today {
+		revenue[row.BranchID] = row.TotalRevenue
+		transactions[row.BranchID] = row.TotalTransactions
+	}
+	for branchID, value := range revenue {
+		label := strconv.Itoa(branchID)
+		ch <- prometheus.MustNewConstMetric(revenueTodayDesc, prometheus.GaugeValue, float64(value), label)
+		ch <- prometheus.MustNewConstMetric(transactionsTodayDesc, prometheus.GaugeValue, float64(transactions[branchID]), label)
+	}
+}
@@ -0,0 +1,106 @@
+// Package metrics exposes Prometheus metrics: HTTP traffic per route, database query timings
+// and pool usage, and business counters for alerting.
+package metrics
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"gorm.io/gorm"
+)
+
+// Registry holds every metric of the service, plus the Go runtime and process collectors
+var Registry = prometheus.NewRegistry()
+
+var (
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_requests_total",
+		Help: "HTTP requests by method, route template and status code.",
+	}, []string{"method", "route", "status"})
+
+	httpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "HTTP request latency by method and route template.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route"})
+
+	dbQueryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "db_query_duration_seconds",
+		Help:    "Database statement latency by operation and table.",
+		Buckets: []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5},
+	}, []string{"operation", "table"})
+
+	transactionsCreated = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "rekap_transactions_created_total",
+		Help: "Transactions created through the API, per branch.",
+	}, []string{"branch_id"})
+
+	paymentToggles = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "rekap_payment_toggles_total",
+		Help: "Payment status toggles, per branch and new status.",
+	}, []string{"branch_id", "status"})
+
+	loginFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "rekap_login_failures_total",
+		Help: "Failed logins by reason.",
+	}, []string{"reason"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequests, httpDuration, dbQueryDuration,
+		transactionsCreated, paymentToggles, loginFailures,
+	)
+}
+
+// Handler serves the metrics in the Prometheus text format
+func Handler() gin.HandlerFunc {
+	h := promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+	return gin.WrapH(h)
+}
+
+// Middleware counts and times every request by its route template, so /transactions/:id is
+// one series rather than one per ID
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		httpRequests.WithLabelValues(c.Request.Method, route, strconv.Itoa(c.Writer.Status())).Inc()
+		httpDuration.WithLabelValues(c.Request.Method, route).Observe(time.Since(start).Seconds())
+	}
+}
+
+// RegisterPool exports the connection pool stats of a database under the given name
+func RegisterPool(name string, db *gorm.DB) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	return Registry.Register(collectors.NewDBStatsCollector(sqlDB, name))
+}
+
+// TransactionCreated counts a transaction created for a branch
+func TransactionCreated(branchID int) {
+	transactionsCreated.WithLabelValues(strconv.Itoa(branchID)).Inc()
+}
+
+// PaymentToggled counts a payment status change of a branch's transaction
+func PaymentToggled(branchID int, status string) {
+	paymentToggles.WithLabelValues(strconv.Itoa(branchID), status).Inc()
+}
+
+// LoginFailed counts a failed login, reason being e.g. "unknown email" or "wrong password"
+func LoginFailed(reason string) {
+	loginFailures.WithLabelValues(reason).Inc()
+}
@@ -1,7 +1,7 @@
 package middleware
 
 import (
-	"net/http"
+	"rekap-backend/apierror"
 	"rekap-backend/auth"
 	"strings"
 
@@ -15,18 +15,14 @@ func AuthMiddleware() gin.HandlerFunc {
 
 		// Check if header exists
 		if authHeader == "" {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
-				"error": "Authorization header is missing",
-			})
+			apierror.Unauthorized(c, "Authorization header is missing")
 			return
 		}
 
 		// Header must be in format "Bearer <token>"
 		parts := strings.SplitN(authHeader, " ", 2)
 		if len(parts) != 2 || strings.ToLower(parts[0]) != "bearer" {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
-				"error": "Invalid Authorization format, use: Bearer <token>",
-			})
+			apierror.Unauthorized(c, "Invalid Authorization format, use: Bearer <token>")
 			return
 		}
 
@@ -35,15 +31,14 @@ func AuthMiddleware() gin.HandlerFunc {
 		// Validate the token
 		claims, err := auth.ValidateAccessToken(tokenString)
 		if err != nil {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
-				"error": "Token is invalid or expired",
-			})
+			apierror.Unauthorized(c, "Token is invalid or expired")
 			return
 		}
 
 		// Store claims in context so handlers can access them
 		c.Set("user_id", claims.UserID)
 		c.Set("email", claims.Email)
+		c.Set("role", claims.Role)
 
 		c.Next()
 	}
@@ -0,0 +1,28 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"rekap-backend/apierror"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// BearerTokenMiddleware requires "Authorization: Bearer <token>" with a fixed token, for
+// machine endpoints such as /metrics. An empty token lets every request through.
+func BearerTokenMiddleware(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if token == "" {
+			c.Next()
+			return
+		}
+
+		given, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+			apierror.Unauthorized(c, "Invalid or missing token")
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"net/http"
+	"rekap-backend/config"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CORSMiddleware lets the configured browser origins call the API and answers preflight requests.
+// Requests from other origins get no CORS headers, so the browser blocks them.
+func CORSMiddleware(cors config.CORSConfig) gin.HandlerFunc {
+	allowed := map[string]bool{}
+	for _, origin := range cors.AllowedOrigins {
+		allowed[origin] = true
+	}
+
+	return func(c *gin.Context) {
+		origin := c.GetHeader("Origin")
+		if origin == "" || (!allowed["*"] && !allowed[origin]) {
+			c.Next()
+			return
+		}
+
+		header := c.Writer.Header()
+		header.Add("Vary", "Origin")
+		if allowed["*"] {
+			header.Set("Access-Control-Allow-Origin", "*")
+		} else {
+			header.Set("Access-Control-Allow-Origin", origin)
+		}
+		if cors.AllowCredentials {
+			header.Set("Access-Control-Allow-Credentials", "true")
+		}
+		header.Set("Access-Control-Expose-Headers", "ETag, X-Cache")
+
+		// Preflight
+		if c.Request.Method == http.MethodOptions && c.GetHeader("Access-Control-Request-Method") != "" {
+			header.Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
+			header.Set("Access-Control-Allow-Headers", "Authorization, Content-Type, If-None-Match")
+			header.Set("Access-Control-Max-Age", strconv.Itoa(int(cors.MaxAge.Seconds())))
+			c.AbortWithStatus(http.StatusNoContent)
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,24 @@
+package middleware
+
+import (
+	"rekap-backend/apierror"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequireRole only lets users with one of the given roles through.
+// It must run after AuthMiddleware, which stores the role from the token.
+func RequireRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role := c.GetString("role")
+
+		for _, allowed := range roles {
+			if role == allowed {
+				c.Next()
+				return
+			}
+		}
+
+		apierror.Forbidden(c, "You do not have permission to access this resource")
+	}
+}
@@ -0,0 +1,127 @@
+package migration
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// sqlFiles holds the forward-only migrations, applied in file name order (NNNN_description.sql)
+//
+//go:embed sql/*.sql
+var sqlFiles embed.FS
+
+// lockKey is the advisory lock that keeps concurrent instances from migrating at the same time
+const lockKey = 72010526
+
+// Migration is a single schema change
+type Migration struct {
+	ID  string
+	SQL string
+}
+
+// All returns every migration in the order it must be applied
+func All() ([]Migration, error) {
+	names, err := fs.Glob(sqlFiles, "sql/*.sql")
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+
+	migrations := make([]Migration, 0, len(names))
+	for _, name := range names {
+		body, err := sqlFiles.ReadFile(name)
+		if err != nil {
+			return nil, err
+		}
+		id := strings.TrimSuffix(strings.TrimPrefix(name, "sql/"), ".sql")
+		migrations = append(migrations, Migration{ID: id, SQL: string(body)})
+	}
+	return migrations, nil
+}
+
+// ensureTable creates the bookkeeping table that records applied migrations
+func ensureTable(db *gorm.DB) error {
+	return db.Exec(`
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			id         VARCHAR(255) PRIMARY KEY,
+			applied_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		)
+	`).Error
+}
+
+// Pending returns the IDs of migrations that have not been applied yet. It only reads, so it is
+// safe to call from probes; without a schema_migrations table every migration is pending.
+func Pending(db *gorm.DB) ([]string, error) {
+	migrations, err := All()
+	if err != nil {
+		return nil, err
+	}
+
+	// Resolved through the search_path, like the CREATE TABLE in ensureTable
+	var exists bool
+	if err := db.Raw("SELECT to_regclass('schema_migrations') IS NOT NULL").Scan(&exists).Error; err != nil {
+		return nil, err
+	}
+
+	var applied []string
+	if exists {
+		if err := db.Table("schema_migrations").Pluck("id", &applied).Error; err != nil {
+			return nil, err
+		}
+	}
+	done := make(map[string]bool, len(applied))
+	for _, id := range applied {
+		done[id] = true
+	}
+
+	var pending []string
+	for _, m := range migrations {
+		if !done[m.ID] {
+			pending = append(pending, m.ID)
+		}
+	}
+	return pending, nil
+}
+
+// Run applies every pending migration, each in its own transaction
+func Run(db *gorm.DB) error {
+	if err := ensureTable(db); err != nil {
+		return fmt.Errorf("create schema_migrations: %w", err)
+	}
+
+	migrations, err := All()
+	if err != nil {
+		return err
+	}
+
+	for _, m := range migrations {
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", lockKey).Error; err != nil {
+				return err
+			}
+
+			// Another instance may have applied it while we waited for the lock
+			var count int64
+			if err := tx.Table("schema_migrations").Where("id = ?", m.ID).Count(&count).Error; err != nil {
+				return err
+			}
+			if count > 0 {
+				return nil
+			}
+
+			if err := tx.Exec(m.SQL).Error; err != nil {
+				return err
+			}
+			return tx.Exec("INSERT INTO schema_migrations (id) VALUES (?)", m.ID).Error
+		})
+		if err != nil {
+			return fmt.Errorf("migration %s: %w", m.ID, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,6 @@
+-- Support keyset pagination on (tanggal_masuk, id), overall and per branch
+CREATE INDEX IF NOT EXISTS idx_transactions_tanggal_masuk_id
+    ON transactions (tanggal_masuk, id);
+
+CREATE INDEX IF NOT EXISTS idx_transactions_branch_tanggal_masuk_id
+    ON transactions (branch_id, tanggal_masuk, id);
@@ -0,0 +1,8 @@
+-- Fuzzy search over customer names and receipt numbers
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE INDEX IF NOT EXISTS idx_transactions_nama_pelanggan_trgm
+    ON transactions USING GIN (nama_pelanggan gin_trgm_ops);
+
+CREATE INDEX IF NOT EXISTS idx_transactions_no_transaksi_trgm
+    ON transactions USING GIN (no_transaksi gin_trgm_ops);
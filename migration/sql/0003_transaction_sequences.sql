@@ -0,0 +1,11 @@
+-- Per-branch, per-business-day receipt number counters
+CREATE TABLE IF NOT EXISTS transaction_sequences (
+    branch_id     INTEGER NOT NULL,
+    business_date DATE    NOT NULL,
+    last_seq      INTEGER NOT NULL,
+    PRIMARY KEY (branch_id, business_date)
+);
+
+-- Exact receipt number lookups
+CREATE INDEX IF NOT EXISTS idx_transactions_no_transaksi
+    ON transactions (no_transaksi);
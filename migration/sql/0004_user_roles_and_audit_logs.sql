@@ -0,0 +1,35 @@
+-- Roles: existing accounts keep full access as owners, new registrations are cashiers
+ALTER TABLE users ADD COLUMN IF NOT EXISTS role VARCHAR(20) NOT NULL DEFAULT 'owner';
+ALTER TABLE users ALTER COLUMN role SET DEFAULT 'cashier';
+
+-- Append-only trail of every data-changing action
+CREATE TABLE IF NOT EXISTS audit_logs (
+    id          BIGSERIAL PRIMARY KEY,
+    user_id     INTEGER,
+    action      VARCHAR(100) NOT NULL,
+    entity      VARCHAR(50)  NOT NULL DEFAULT '',
+    entity_id   VARCHAR(100) NOT NULL DEFAULT '',
+    changes     JSONB,
+    metadata    JSONB,
+    method      VARCHAR(10)  NOT NULL,
+    path        TEXT         NOT NULL,
+    status_code INTEGER      NOT NULL,
+    ip_address  VARCHAR(64)  NOT NULL DEFAULT '',
+    user_agent  TEXT         NOT NULL DEFAULT '',
+    created_at  TIMESTAMPTZ  NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_logs_created_at ON audit_logs (created_at);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_user_id ON audit_logs (user_id);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_entity ON audit_logs (entity, entity_id);
+
+CREATE OR REPLACE FUNCTION audit_logs_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_logs is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS audit_logs_append_only ON audit_logs;
+CREATE TRIGGER audit_logs_append_only
+    BEFORE UPDATE OR DELETE OR TRUNCATE ON audit_logs
+    FOR EACH STATEMENT EXECUTE FUNCTION audit_logs_append_only();
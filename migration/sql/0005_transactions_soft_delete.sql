@@ -0,0 +1,8 @@
+-- Soft delete: deleted rows stay in the table until an owner purges them
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS deleted_at    TIMESTAMPTZ;
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS deleted_by    INTEGER;
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS delete_reason TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_transactions_deleted_at
+    ON transactions (deleted_at)
+    WHERE deleted_at IS NOT NULL;
@@ -0,0 +1,9 @@
+-- Money columns hold whole rupiah as BIGINT so SUMs are exact
+ALTER TABLE transactions
+    ALTER COLUMN dp                 TYPE BIGINT USING ROUND(dp)::BIGINT,
+    ALTER COLUMN pelunasan          TYPE BIGINT USING ROUND(pelunasan)::BIGINT,
+    ALTER COLUMN subtotal           TYPE BIGINT USING ROUND(subtotal)::BIGINT,
+    ALTER COLUMN biaya_antar_jemput TYPE BIGINT USING ROUND(biaya_antar_jemput)::BIGINT,
+    ALTER COLUMN diskon             TYPE BIGINT USING ROUND(diskon)::BIGINT,
+    ALTER COLUMN diskon_poin        TYPE BIGINT USING ROUND(diskon_poin)::BIGINT,
+    ALTER COLUMN total              TYPE BIGINT USING ROUND(total)::BIGINT;
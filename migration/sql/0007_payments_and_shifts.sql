@@ -0,0 +1,32 @@
+-- Ledger of money taken at the counter, so cash can be reconciled per shift
+CREATE TABLE IF NOT EXISTS payments (
+    id             BIGSERIAL PRIMARY KEY,
+    transaction_id INTEGER     NOT NULL,
+    branch_id      INTEGER     NOT NULL,
+    amount         BIGINT      NOT NULL, -- Whole rupiah, negative for reversals
+    kind           VARCHAR(20) NOT NULL, -- dp, pelunasan, reversal
+    received_by    INTEGER,
+    received_at    TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_payments_branch_received_at ON payments (branch_id, received_at);
+CREATE INDEX IF NOT EXISTS idx_payments_transaction_id ON payments (transaction_id);
+
+-- Cash drawer shifts
+CREATE TABLE IF NOT EXISTS shifts (
+    id            BIGSERIAL PRIMARY KEY,
+    branch_id     INTEGER     NOT NULL,
+    user_id       INTEGER     NOT NULL,
+    opening_float BIGINT      NOT NULL,
+    opened_at     TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    closed_at     TIMESTAMPTZ,
+    closed_by     INTEGER,
+    expected_cash BIGINT,
+    counted_cash  BIGINT,
+    variance      BIGINT,
+    note          TEXT        NOT NULL DEFAULT ''
+);
+
+-- A branch has one drawer, so only one open shift at a time
+CREATE UNIQUE INDEX IF NOT EXISTS idx_shifts_one_open_per_branch ON shifts (branch_id) WHERE closed_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_shifts_branch_opened_at ON shifts (branch_id, opened_at);
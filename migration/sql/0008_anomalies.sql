@@ -0,0 +1,15 @@
+-- Flagged daily branch figures, one row per branch, business date and metric
+CREATE TABLE IF NOT EXISTS anomalies (
+    id            BIGSERIAL PRIMARY KEY,
+    branch_id     INTEGER          NOT NULL,
+    business_date DATE             NOT NULL,
+    metric        VARCHAR(50)      NOT NULL,
+    value         DOUBLE PRECISION NOT NULL,
+    baseline      DOUBLE PRECISION NOT NULL,
+    score         DOUBLE PRECISION NOT NULL,
+    explanation   TEXT             NOT NULL,
+    detected_at   TIMESTAMPTZ      NOT NULL DEFAULT NOW(),
+    UNIQUE (branch_id, business_date, metric)
+);
+
+CREATE INDEX IF NOT EXISTS idx_anomalies_business_date ON anomalies (business_date);
@@ -0,0 +1,26 @@
+-- Per-branch, per-business-date aggregates of live transactions, kept in step with every write.
+-- Business dates depend on BUSINESS_TIMEZONE and BUSINESS_DAY_CUTOFF_HOUR, so the application
+-- fills the table (see stats.EnsureRollup) and records the settings it was built with.
+CREATE TABLE IF NOT EXISTS daily_branch_stats (
+    branch_id          INTEGER          NOT NULL,
+    business_date      DATE             NOT NULL,
+    total_transactions BIGINT           NOT NULL DEFAULT 0,
+    total_revenue      BIGINT           NOT NULL DEFAULT 0,
+    total_kg           DOUBLE PRECISION NOT NULL DEFAULT 0,
+    total_pc           BIGINT           NOT NULL DEFAULT 0,
+    total_paid         BIGINT           NOT NULL DEFAULT 0,
+    total_discount     BIGINT           NOT NULL DEFAULT 0,
+    discounted_orders  BIGINT           NOT NULL DEFAULT 0,
+    updated_at         TIMESTAMPTZ      NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (branch_id, business_date)
+);
+
+CREATE INDEX IF NOT EXISTS idx_daily_branch_stats_business_date ON daily_branch_stats (business_date);
+
+-- Single row: the business-day settings daily_branch_stats was last fully built with
+CREATE TABLE IF NOT EXISTS daily_branch_stats_state (
+    id          BOOLEAN     PRIMARY KEY DEFAULT TRUE CHECK (id),
+    timezone    TEXT        NOT NULL,
+    cutoff_hour INTEGER     NOT NULL,
+    built_at    TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,21 @@
+package model
+
+import "time"
+
+// Anomaly is a daily branch figure that deviates strongly from its rolling baseline
+type Anomaly struct {
+	ID           int64     `gorm:"primaryKey;autoIncrement" json:"id"`
+	BranchID     int       `gorm:"column:branch_id" json:"branch_id"`
+	BusinessDate string    `gorm:"column:business_date;type:date" json:"business_date"` // YYYY-MM-DD
+	Metric       string    `gorm:"column:metric" json:"metric"`
+	Value        float64   `gorm:"column:value" json:"value"`
+	Baseline     float64   `gorm:"column:baseline" json:"baseline"` // Median of the same weekday in previous weeks
+	Score        float64   `gorm:"column:score" json:"score"`       // Robust z-score, negative for drops
+	Explanation  string    `gorm:"column:explanation" json:"explanation"`
+	DetectedAt   time.Time `gorm:"column:detected_at" json:"detected_at"`
+}
+
+// TableName specifies the database table name for GORM
+func (Anomaly) TableName() string {
+	return "anomalies"
+}
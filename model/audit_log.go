@@ -0,0 +1,25 @@
+package model
+
+import "time"
+
+// AuditLog is one append-only record of a data-changing action
+type AuditLog struct {
+	ID         int64     `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserID     *int      `gorm:"column:user_id" json:"user_id"` // Actor, nil for anonymous events such as a failed login
+	Action     string    `gorm:"column:action" json:"action"`
+	Entity     string    `gorm:"column:entity" json:"entity"`
+	EntityID   string    `gorm:"column:entity_id" json:"entity_id"`
+	Changes    JSONText  `gorm:"column:changes" json:"changes"` // Field-level before/after diff
+	Metadata   JSONText  `gorm:"column:metadata" json:"metadata"`
+	Method     string    `gorm:"column:method" json:"method"`
+	Path       string    `gorm:"column:path" json:"path"`
+	StatusCode int       `gorm:"column:status_code" json:"status_code"`
+	IPAddress  string    `gorm:"column:ip_address" json:"ip_address"`
+	UserAgent  string    `gorm:"column:user_agent" json:"user_agent"`
+	CreatedAt  time.Time `gorm:"column:created_at" json:"created_at"`
+}
+
+// TableName specifies the database table name for GORM
+func (AuditLog) TableName() string {
+	return "audit_logs"
+}
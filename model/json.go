@@ -0,0 +1,40 @@
+package model
+
+import (
+	"database/sql/driver"
+	"errors"
+)
+
+// JSONText is a JSON document stored in a jsonb column and embedded as-is in API responses
+type JSONText []byte
+
+// Value implements driver.Valuer
+func (j JSONText) Value() (driver.Value, error) {
+	if len(j) == 0 {
+		return nil, nil
+	}
+	return string(j), nil
+}
+
+// Scan implements sql.Scanner
+func (j *JSONText) Scan(src any) error {
+	switch v := src.(type) {
+	case nil:
+		*j = nil
+	case []byte:
+		*j = append((*j)[:0], v...)
+	case string:
+		*j = JSONText(v)
+	default:
+		return errors.New("JSONText: unsupported source type")
+	}
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler
+func (j JSONText) MarshalJSON() ([]byte, error) {
+	if len(j) == 0 {
+		return []byte("null"), nil
+	}
+	return j, nil
+}
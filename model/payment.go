@@ -0,0 +1,26 @@
+package model
+
+import "time"
+
+// Payment kinds
+const (
+	PaymentKindDP        = "dp"
+	PaymentKindPelunasan = "pelunasan"
+	PaymentKindReversal  = "reversal"
+)
+
+// Payment is one movement of money at the counter for a transaction
+type Payment struct {
+	ID            int64     `gorm:"primaryKey;autoIncrement" json:"id"`
+	TransactionID int       `gorm:"column:transaction_id" json:"transaction_id"`
+	BranchID      int       `gorm:"column:branch_id" json:"branch_id"`
+	Amount        int64     `gorm:"column:amount" json:"amount"` // Whole rupiah, negative for reversals
+	Kind          string    `gorm:"column:kind" json:"kind"`
+	ReceivedBy    *int      `gorm:"column:received_by" json:"received_by"`
+	ReceivedAt    time.Time `gorm:"column:received_at" json:"received_at"`
+}
+
+// TableName specifies the database table name for GORM
+func (Payment) TableName() string {
+	return "payments"
+}
@@ -0,0 +1,23 @@
+package model
+
+import "time"
+
+// Shift is one cashier's session at a branch's cash drawer. Amounts are whole rupiah.
+type Shift struct {
+	ID           int64      `gorm:"primaryKey;autoIncrement" json:"id"`
+	BranchID     int        `gorm:"column:branch_id" json:"branch_id"`
+	UserID       int        `gorm:"column:user_id" json:"user_id"`
+	OpeningFloat int64      `gorm:"column:opening_float" json:"opening_float"`
+	OpenedAt     time.Time  `gorm:"column:opened_at" json:"opened_at"`
+	ClosedAt     *time.Time `gorm:"column:closed_at" json:"closed_at"`
+	ClosedBy     *int       `gorm:"column:closed_by" json:"closed_by"`
+	ExpectedCash *int64     `gorm:"column:expected_cash" json:"expected_cash"` // Opening float plus payments taken during the shift
+	CountedCash  *int64     `gorm:"column:counted_cash" json:"counted_cash"`
+	Variance     *int64     `gorm:"column:variance" json:"variance"` // Counted minus expected, negative is a shortage
+	Note         string     `gorm:"column:note" json:"note"`
+}
+
+// TableName specifies the database table name for GORM
+func (Shift) TableName() string {
+	return "shifts"
+}
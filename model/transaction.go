@@ -1,8 +1,13 @@
 package model
 
-import "time"
+import (
+	"time"
 
-// Transaction represents a single laundry transaction record
+	"gorm.io/gorm"
+)
+
+// Transaction represents a single laundry transaction record.
+// Money fields are whole rupiah, stored as BIGINT so sums stay exact.
 type Transaction struct {
 	ID               int       `gorm:"primaryKey;autoIncrement" json:"id"`
 	BranchID         int       `gorm:"column:branch_id" json:"branch_id"`
@@ -11,16 +16,21 @@ type Transaction struct {
 	NamaPelanggan    string    `gorm:"column:nama_pelanggan" json:"nama_pelanggan"`
 	Status           string    `gorm:"column:status" json:"status"`
 	StatusPembayaran string    `gorm:"column:status_pembayaran" json:"status_pembayaran"`
-	DP               float64   `gorm:"column:dp" json:"dp"`
-	Pelunasan        float64   `gorm:"column:pelunasan" json:"pelunasan"`
-	Subtotal         float64   `gorm:"column:subtotal" json:"subtotal"`
-	BiayaAntarJemput float64   `gorm:"column:biaya_antar_jemput" json:"biaya_antar_jemput"`
-	Diskon           float64   `gorm:"column:diskon" json:"diskon"`
-	DiskonPoin       float64   `gorm:"column:diskon_poin" json:"diskon_poin"`
-	Total            float64   `gorm:"column:total" json:"total"`
+	DP               int64     `gorm:"column:dp" json:"dp"`
+	Pelunasan        int64     `gorm:"column:pelunasan" json:"pelunasan"`
+	Subtotal         int64     `gorm:"column:subtotal" json:"subtotal"`
+	BiayaAntarJemput int64     `gorm:"column:biaya_antar_jemput" json:"biaya_antar_jemput"`
+	Diskon           int64     `gorm:"column:diskon" json:"diskon"`
+	DiskonPoin       int64     `gorm:"column:diskon_poin" json:"diskon_poin"`
+	Total            int64     `gorm:"column:total" json:"total"`
 	JumlahKg         float64   `gorm:"column:jumlah_kg" json:"jumlah_kg"`
 	JumlahPc         int       `gorm:"column:jumlah_pc" json:"jumlah_pc"`
 	CreatedAt        time.Time `gorm:"column:created_at" json:"created_at"`
+
+	// Soft delete: GORM excludes rows with deleted_at set unless the query is Unscoped
+	DeletedAt    gorm.DeletedAt `gorm:"column:deleted_at" json:"deleted_at"`
+	DeletedBy    *int           `gorm:"column:deleted_by" json:"deleted_by"`
+	DeleteReason *string        `gorm:"column:delete_reason" json:"delete_reason"`
 }
 
 // TableName specifies the database table name for GORM
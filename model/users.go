@@ -2,13 +2,20 @@ package model
 
 import "time"
 
+// User roles
+const (
+	RoleOwner   = "owner"
+	RoleCashier = "cashier"
+)
+
 type Users struct {
-	ID           int       `gorm:"primaryKey;autoIncrement" json:"id"`
-	Email        string    `gorm:"column:email" json:"email"`
-	Password     string    `gorm:"column:password" json:"-"`
-	Name         string    `gorm:"column:name" json:"name"`
-	CreatedAt    time.Time `gorm:"column:created_at" json:"created_at"`
-	UpdatedAt    time.Time `gorm:"column:updated_at" json:"updated_at"`
+	ID        int       `gorm:"primaryKey;autoIncrement" json:"id"`
+	Email     string    `gorm:"column:email" json:"email"`
+	Password  string    `gorm:"column:password" json:"-"`
+	Name      string    `gorm:"column:name" json:"name"`
+	Role      string    `gorm:"column:role;default:cashier" json:"role"`
+	CreatedAt time.Time `gorm:"column:created_at" json:"created_at"`
+	UpdatedAt time.Time `gorm:"column:updated_at" json:"updated_at"`
 }
 
 func (Users) TableName() string {
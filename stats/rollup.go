@@ -0,0 +1,278 @@
+package stats
+
+import (
+	"fmt"
+	"math"
+	"rekap-backend/businessday"
+	"rekap-backend/config"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// rollupColumns lists the daily_branch_stats columns filled from aggregateColumns
+const rollupColumns = `branch_id, business_date, total_transactions, total_revenue, total_kg,
+	total_pc, total_paid, total_discount, discounted_orders`
+
+// QueryRollup reads the aggregates of the business dates from..to (inclusive) from
+// daily_branch_stats, ordered by date then branch
+func QueryRollup(db *gorm.DB, from, to time.Time, branchIDs []int) ([]Daily, error) {
+	query := db.Table("daily_branch_stats").
+		Where("business_date BETWEEN ? AND ?", from.Format("2006-01-02"), to.Format("2006-01-02"))
+	if len(branchIDs) > 0 {
+		query = query.Where("branch_id IN ?", branchIDs)
+	}
+
+	var rows []Daily
+	err := query.Select(`
+		branch_id, TO_CHAR(business_date, 'YYYY-MM-DD') as date, total_transactions, total_revenue,
+		total_kg, total_pc, total_paid, total_discount, discounted_orders
+	`).Order("business_date ASC, branch_id ASC").
+		Scan(&rows).Error
+
+	return rows, err
+}
+
+// Refresh recomputes one branch's row for one business date (YYYY-MM-DD) from the live
+// transactions. Call it inside the transaction that changed them, so the rollup commits with it.
+func Refresh(tx *gorm.DB, branchID int, date string) error {
+	day, err := businessday.ParseDate(date)
+	if err != nil {
+		return err
+	}
+	window := businessday.WindowOf(day, day)
+
+	// Serialize refreshes of the same row, otherwise a concurrent write could overwrite it with
+	// totals computed before this transaction's change was visible
+	err = tx.Exec("SELECT pg_advisory_xact_lock(hashtext('daily_branch_stats'), hashtext(?))",
+		fmt.Sprintf("%d/%s", branchID, date)).Error
+	if err != nil {
+		return err
+	}
+
+	// Without GROUP BY the aggregate always yields a row, zeros included
+	err = tx.Exec(`
+		INSERT INTO daily_branch_stats (`+rollupColumns+`)
+		SELECT ?::INTEGER, ?::DATE, `+aggregateColumns+`
+		FROM transactions
+		WHERE branch_id = ? AND deleted_at IS NULL AND tanggal_masuk >= ? AND tanggal_masuk < ?
+		ON CONFLICT (branch_id, business_date) DO UPDATE SET
+			total_transactions = EXCLUDED.total_transactions,
+			total_revenue      = EXCLUDED.total_revenue,
+			total_kg           = EXCLUDED.total_kg,
+			total_pc           = EXCLUDED.total_pc,
+			total_paid         = EXCLUDED.total_paid,
+			total_discount     = EXCLUDED.total_discount,
+			discounted_orders  = EXCLUDED.discounted_orders,
+			updated_at         = NOW()
+	`, branchID, date, branchID, window.Start, window.End).Error
+	if err != nil {
+		return err
+	}
+
+	// Days without live transactions are not stored, same as QueryDaily
+	return tx.Exec("DELETE FROM daily_branch_stats WHERE branch_id = ? AND business_date = ? AND total_transactions = 0",
+		branchID, date).Error
+}
+
+// RefreshTransactions refreshes the rows of every branch and business date the given
+// transactions (deleted ones included) belong to
+func RefreshTransactions(tx *gorm.DB, ids []int) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	var keys []struct {
+		BranchID int
+		Date     string
+	}
+	err := tx.Table("transactions").
+		Select("DISTINCT branch_id, TO_CHAR("+businessday.DateExpr()+", 'YYYY-MM-DD') as date").
+		Where("id IN ?", ids).
+		Scan(&keys).Error
+	if err != nil {
+		return err
+	}
+
+	for _, key := range keys {
+		if err := Refresh(tx, key.BranchID, key.Date); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Rebuild recomputes daily_branch_stats for the business dates from..to (inclusive), e.g. after
+// a bulk import. It returns the number of rows written.
+func Rebuild(db *gorm.DB, from, to time.Time) (int64, error) {
+	var written int64
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		written, err = rebuild(tx, &dateRange{from, to})
+		return err
+	})
+	return written, err
+}
+
+// RebuildAll recomputes the whole of daily_branch_stats and records the business-day settings
+// it was built with
+func RebuildAll(db *gorm.DB) (int64, error) {
+	var written int64
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if written, err = rebuild(tx, nil); err != nil {
+			return err
+		}
+		return saveState(tx)
+	})
+	return written, err
+}
+
+// EnsureRollup rebuilds daily_branch_stats when it was never built, or built with a different
+// BUSINESS_TIMEZONE or BUSINESS_DAY_CUTOFF_HOUR, since every business date may have shifted.
+// It reports whether a rebuild ran.
+func EnsureRollup(db *gorm.DB) (bool, error) {
+	if current, err := stateMatches(db); err != nil || current {
+		return false, err
+	}
+
+	rebuilt := false
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := lockRollup(tx); err != nil {
+			return err
+		}
+
+		// Another instance may have rebuilt it while we waited for the lock
+		current, err := stateMatches(tx)
+		if err != nil || current {
+			return err
+		}
+
+		if _, err := rebuild(tx, nil); err != nil {
+			return err
+		}
+		rebuilt = true
+		return saveState(tx)
+	})
+	return rebuilt, err
+}
+
+// lockRollup blocks writers, including Refresh, until the transaction ends. Readers are not blocked.
+func lockRollup(tx *gorm.DB) error {
+	return tx.Exec("LOCK TABLE daily_branch_stats IN EXCLUSIVE MODE").Error
+}
+
+// dateRange is an inclusive range of business dates
+type dateRange struct {
+	from, to time.Time
+}
+
+// rebuild replaces the rollup rows of the given dates, or of every date when dates is nil
+func rebuild(tx *gorm.DB, dates *dateRange) (int64, error) {
+	if err := lockRollup(tx); err != nil {
+		return 0, err
+	}
+
+	source := tx.Table("transactions").Where("deleted_at IS NULL")
+	clear := tx.Exec("DELETE FROM daily_branch_stats")
+	if dates != nil {
+		window := businessday.WindowOf(dates.from, dates.to)
+		source = source.Where("tanggal_masuk >= ? AND tanggal_masuk < ?", window.Start, window.End)
+		clear = tx.Exec("DELETE FROM daily_branch_stats WHERE business_date BETWEEN ? AND ?",
+			dates.from.Format("2006-01-02"), dates.to.Format("2006-01-02"))
+	}
+	if clear.Error != nil {
+		return 0, clear.Error
+	}
+
+	dateExpr := businessday.DateExpr()
+	insert := tx.Exec("INSERT INTO daily_branch_stats ("+rollupColumns+") ?",
+		source.Select("branch_id, "+dateExpr+", "+aggregateColumns).Group("branch_id, "+dateExpr))
+	return insert.RowsAffected, insert.Error
+}
+
+// stateMatches reports whether the rollup was built with the current business-day settings
+func stateMatches(db *gorm.DB) (bool, error) {
+	var count int64
+	err := db.Table("daily_branch_stats_state").
+		Where("timezone = ? AND cutoff_hour = ?", config.BusinessLocation.String(), config.BusinessDayCutoffHour).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// saveState records the current business-day settings as the ones the rollup was built with
+func saveState(tx *gorm.DB) error {
+	return tx.Exec(`
+		INSERT INTO daily_branch_stats_state (id, timezone, cutoff_hour, built_at)
+		VALUES (TRUE, ?, ?, NOW())
+		ON CONFLICT (id) DO UPDATE SET
+			timezone = EXCLUDED.timezone, cutoff_hour = EXCLUDED.cutoff_hour, built_at = EXCLUDED.built_at
+	`, config.BusinessLocation.String(), config.BusinessDayCutoffHour).Error
+}
+
+// Mismatch is a branch and business date where the rollup disagrees with the live transactions.
+// Rollup or Live is nil when that side has no row.
+type Mismatch struct {
+	BranchID int    `json:"branch_id"`
+	Date     string `json:"date"`
+	Rollup   *Daily `json:"rollup"`
+	Live     *Daily `json:"live"`
+}
+
+// Check compares daily_branch_stats with live aggregation for the business dates from..to
+// (inclusive) and returns every difference
+func Check(db *gorm.DB, from, to time.Time) ([]Mismatch, error) {
+	live, err := QueryDaily(db, businessday.WindowOf(from, to), nil)
+	if err != nil {
+		return nil, err
+	}
+	rollup, err := QueryRollup(db, from, to, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	type key struct {
+		branchID int
+		date     string
+	}
+	mismatches := map[key]*Mismatch{}
+	order := []key{}
+	entry := func(row Daily) *Mismatch {
+		k := key{row.BranchID, row.Date}
+		if mismatches[k] == nil {
+			mismatches[k] = &Mismatch{BranchID: row.BranchID, Date: row.Date}
+			order = append(order, k)
+		}
+		return mismatches[k]
+	}
+	for i := range live {
+		entry(live[i]).Live = &live[i]
+	}
+	for i := range rollup {
+		entry(rollup[i]).Rollup = &rollup[i]
+	}
+
+	sort.Slice(order, func(i, j int) bool {
+		if order[i].date != order[j].date {
+			return order[i].date < order[j].date
+		}
+		return order[i].branchID < order[j].branchID
+	})
+
+	result := []Mismatch{}
+	for _, k := range order {
+		m := mismatches[k]
+		if m.Live == nil || m.Rollup == nil || !sameFigures(*m.Live, *m.Rollup) {
+			result = append(result, *m)
+		}
+	}
+	return result, nil
+}
+
+// sameFigures compares two aggregates, allowing for float rounding in the kg sum
+func sameFigures(a, b Daily) bool {
+	kgA, kgB := a.TotalKg, b.TotalKg
+	a.TotalKg, b.TotalKg = 0, 0
+	return a == b && math.Abs(kgA-kgB) < 1e-6
+}
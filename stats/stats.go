@@ -0,0 +1,72 @@
+// Package stats provides per-branch, per-business-date aggregates of live transactions.
+package stats
+
+import (
+	"rekap-backend/businessday"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Daily holds one branch's aggregates for one business date. Money is whole rupiah.
+type Daily struct {
+	BranchID          int     `json:"branch_id"`
+	Date              string  `json:"date"` // Business date, YYYY-MM-DD
+	TotalTransactions int64   `json:"total_transactions"`
+	TotalRevenue      int64   `json:"total_revenue"`
+	TotalKg           float64 `json:"total_kg"`
+	TotalPc           int64   `json:"total_pc"`
+	TotalPaid         int64   `json:"total_paid"`     // Transactions with status_pembayaran = 'lunas'
+	TotalDiscount     int64   `json:"total_discount"` // diskon + diskon_poin
+	DiscountedOrders  int64   `json:"discounted_orders"`
+}
+
+// aggregateColumns computes the Daily figures over a set of transactions, in column order of
+// daily_branch_stats
+const aggregateColumns = `
+	COUNT(*) as total_transactions,
+	COALESCE(SUM(total), 0)::BIGINT as total_revenue,
+	COALESCE(SUM(jumlah_kg), 0) as total_kg,
+	COALESCE(SUM(jumlah_pc), 0)::BIGINT as total_pc,
+	COUNT(CASE WHEN status_pembayaran = 'lunas' THEN 1 END) as total_paid,
+	COALESCE(SUM(diskon + diskon_poin), 0)::BIGINT as total_discount,
+	COUNT(CASE WHEN diskon + diskon_poin > 0 THEN 1 END) as discounted_orders
+`
+
+// Source selects where aggregates are read from
+type Source string
+
+const (
+	SourceRollup Source = "rollup" // The daily_branch_stats table, see Refresh
+	SourceLive   Source = "live"   // Aggregated from transactions on the fly
+)
+
+// Query returns the aggregates of the business dates from..to (inclusive) from the given source,
+// ordered by date then branch
+func Query(db *gorm.DB, source Source, from, to time.Time, branchIDs []int) ([]Daily, error) {
+	if source == SourceLive {
+		return QueryDaily(db, businessday.WindowOf(from, to), branchIDs)
+	}
+	return QueryRollup(db, from, to, branchIDs)
+}
+
+// QueryDaily aggregates live transactions per branch and business date within a window,
+// ordered by date then branch. Days without transactions are not returned.
+func QueryDaily(db *gorm.DB, window businessday.Window, branchIDs []int) ([]Daily, error) {
+	dateExpr := "TO_CHAR(" + businessday.DateExpr() + ", 'YYYY-MM-DD')"
+
+	query := db.Table("transactions").
+		Where("deleted_at IS NULL").
+		Where("tanggal_masuk >= ? AND tanggal_masuk < ?", window.Start, window.End)
+	if len(branchIDs) > 0 {
+		query = query.Where("branch_id IN ?", branchIDs)
+	}
+
+	var rows []Daily
+	err := query.Select("branch_id, " + dateExpr + " as date, " + aggregateColumns).
+		Group("branch_id, " + dateExpr).
+		Order("date ASC, branch_id ASC").
+		Scan(&rows).Error
+
+	return rows, err
+}
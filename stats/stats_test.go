@@ -0,0 +1,104 @@
+package stats
+
+import (
+	"math/big"
+	"os"
+	"regexp"
+	"rekap-backend/businessday"
+	"testing"
+	"time"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestQueryDailySumsMoneyAsBigint(t *testing.T) {
+	// A dry run builds the statement without a database
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=127.0.0.1"}),
+		&gorm.Config{DryRun: true, DisableAutomaticPing: true, Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sql string
+	err = db.Callback().Row().After("gorm:row").Register("test:capture", func(tx *gorm.DB) {
+		sql = tx.Statement.SQL.String()
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	day := time.Date(2026, 1, 16, 0, 0, 0, 0, time.UTC)
+	QueryDaily(db, businessday.WindowOf(day, day), nil)
+	if sql == "" {
+		t.Fatal("no statement was built")
+	}
+
+	// Postgres sums BIGINT into NUMERIC; casting back keeps the scan into int64 exact
+	for _, column := range []string{"total_revenue", "total_pc", "total_discount"} {
+		pattern := regexp.MustCompile(`COALESCE\(SUM\([^)]+\), 0\)::BIGINT as ` + column + `\b`)
+		if !pattern.MatchString(sql) {
+			t.Errorf("%s is not summed as COALESCE(SUM(...), 0)::BIGINT in\n%s", column, sql)
+		}
+	}
+}
+
+// TestQueryDailyExact sums amounts above 2^53, where float64 loses whole rupiah, and expects them
+// to the rupiah. It needs a Postgres database: set TEST_DATABASE_DSN to run it.
+func TestQueryDailyExact(t *testing.T) {
+	dsn := os.Getenv("TEST_DATABASE_DSN")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_DSN is not set")
+	}
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A temporary table shadows transactions for this transaction only
+	tx := db.Begin()
+	t.Cleanup(func() { tx.Rollback() })
+	err = tx.Exec(`CREATE TEMP TABLE transactions (
+		branch_id INT, tanggal_masuk TIMESTAMP, total BIGINT, jumlah_kg NUMERIC, jumlah_pc INT,
+		status_pembayaran TEXT, diskon BIGINT, diskon_poin BIGINT, deleted_at TIMESTAMP
+	) ON COMMIT DROP`).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	day := time.Date(2026, 1, 16, 0, 0, 0, 0, time.UTC)
+	window := businessday.WindowOf(day, day)
+	at := window.Start.Add(time.Hour)
+
+	const big53 = int64(1) << 53
+	amounts := []int64{big53 + 1, big53 + 3, 7, big53 - 1, 250_000}
+	revenue, discount := new(big.Int), new(big.Int)
+	for _, amount := range amounts {
+		err := tx.Exec(`INSERT INTO transactions
+			(branch_id, tanggal_masuk, total, jumlah_kg, jumlah_pc, status_pembayaran, diskon, diskon_poin)
+			VALUES (1, ?, ?, 1.5, 2, 'lunas', ?, 1)`, at, amount, amount/4).Error
+		if err != nil {
+			t.Fatal(err)
+		}
+		revenue.Add(revenue, big.NewInt(amount))
+		discount.Add(discount, big.NewInt(amount/4+1))
+	}
+
+	rows, err := QueryDaily(tx, window, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 1 {
+		t.Fatalf("got %d rows, want 1: %+v", len(rows), rows)
+	}
+	got := rows[0]
+	if got.TotalRevenue != revenue.Int64() {
+		t.Errorf("total_revenue = %d, want %s", got.TotalRevenue, revenue)
+	}
+	if got.TotalDiscount != discount.Int64() {
+		t.Errorf("total_discount = %d, want %s", got.TotalDiscount, discount)
+	}
+	if got.TotalTransactions != int64(len(amounts)) || got.TotalPc != int64(2*len(amounts)) {
+		t.Errorf("got %+v, want %d transactions and %d pieces", got, len(amounts), 2*len(amounts))
+	}
+}
@@ -0,0 +1,115 @@
+// Package tracing sets up OpenTelemetry tracing: a span per Gin route (otelgin), a child span
+// per GORM statement, and W3C trace context propagation in and out.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"rekap-backend/logging"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName identifies the spans created by this service's own instrumentation
+const instrumentationName = "rekap-backend"
+
+// Exporters
+const (
+	ExporterNone   = "none"   // Tracing off
+	ExporterStdout = "stdout" // Pretty-printed spans on stdout, for local development
+	ExporterOTLP   = "otlp"   // OTLP over HTTP, configured with the standard OTEL_EXPORTER_OTLP_* variables
+)
+
+// Setup installs the global tracer provider and propagator. The returned function flushes
+// pending spans and must be called on shutdown.
+func Setup(ctx context.Context, exporterName, serviceName string, sampleRatio float64) (func(context.Context) error, error) {
+	// Propagate W3C trace context even when not recording, so upstream traces stay connected
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{}, propagation.Baggage{},
+	))
+
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch exporterName {
+	case ExporterNone:
+		return func(context.Context) error { return nil }, nil
+	case ExporterStdout:
+		exporter, err = stdouttrace.New(stdouttrace.WithPrettyPrint())
+	case ExporterOTLP:
+		exporter, err = otlptracehttp.New(ctx)
+	default:
+		return nil, fmt.Errorf("unknown trace exporter %q", exporterName)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("create %s exporter: %w", exporterName, err)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(
+		semconv.SchemaURL, semconv.ServiceName(serviceName),
+	))
+	if err != nil {
+		return nil, err
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(sampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+
+	return provider.Shutdown, nil
+}
+
+// untraced are the probe and scrape endpoints, which would only add noise
+var untraced = map[string]bool{"/healthz": true, "/readyz": true, "/metrics": true, "/ping": true}
+
+// Middleware starts a server span per request, named after the route template, and continues
+// the caller's trace from the traceparent header. Register it before the handlers.
+func Middleware(serviceName string) gin.HandlerFunc {
+	return otelgin.Middleware(serviceName, otelgin.WithGinFilter(func(c *gin.Context) bool {
+		return !untraced[c.FullPath()]
+	}))
+}
+
+// RequestAttributes adds what the request was about to its span once the handler ran: branch,
+// requested date range and request ID. It also hands the trace ID to the access log. Register it
+// right after Middleware.
+func RequestAttributes() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if sc := trace.SpanContextFromContext(c.Request.Context()); sc.IsValid() {
+			logging.SetTraceID(c, sc.TraceID().String())
+		}
+
+		c.Next()
+
+		span := trace.SpanFromContext(c.Request.Context())
+		if !span.IsRecording() {
+			return
+		}
+
+		attrs := []attribute.KeyValue{attribute.String("request.id", c.GetString("request_id"))}
+		if branchID, ok := logging.Branch(c); ok {
+			attrs = append(attrs, attribute.Int("rekap.branch_id", branchID))
+		}
+		for _, param := range []string{"date", "start_date", "end_date", "compare_start_date", "compare_end_date"} {
+			if v := c.Query(param); v != "" {
+				attrs = append(attrs, attribute.String("rekap."+param, v))
+			}
+		}
+		if userID, ok := c.Get("user_id"); ok {
+			attrs = append(attrs, attribute.Int("enduser.id", userID.(int)))
+		}
+		span.SetAttributes(attrs...)
+	}
+}
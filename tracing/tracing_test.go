@@ -0,0 +1,63 @@
+package tracing
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"rekap-backend/logging"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// callerTrace is the trace ID of the traceparent sent by the tests
+const callerTrace = "4bf92f3577b34da6a3ce929d0e0e4736"
+
+func TestAccessLogCarriesTraceID(t *testing.T) {
+	// Record every span and read traceparent, as Setup does with an exporter
+	previousProvider, previousPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider())
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	var logs bytes.Buffer
+	previousLogger := slog.Default()
+	slog.SetDefault(slog.New(slog.NewJSONHandler(&logs, nil)))
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previousProvider)
+		otel.SetTextMapPropagator(previousPropagator)
+		slog.SetDefault(previousLogger)
+	})
+
+	// The same order as main
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(logging.Middleware(), logging.Recovery())
+	r.Use(Middleware("test"), RequestAttributes())
+	r.GET("/api/ok", func(c *gin.Context) { c.Status(http.StatusNoContent) })
+	r.GET("/api/panic", func(c *gin.Context) { panic("boom") })
+
+	for _, path := range []string{"/api/ok", "/api/panic"} {
+		logs.Reset()
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("traceparent", "00-"+callerTrace+"-00f067aa0ba902b7-01")
+		r.ServeHTTP(httptest.NewRecorder(), req)
+
+		lines := bytes.Split(bytes.TrimSpace(logs.Bytes()), []byte("\n"))
+		if len(lines) == 0 || len(lines[0]) == 0 {
+			t.Fatalf("%s: nothing logged", path)
+		}
+		for _, line := range lines {
+			var entry map[string]any
+			if err := json.Unmarshal(line, &entry); err != nil {
+				t.Fatalf("%s: %v in %s", path, err, line)
+			}
+			if entry["trace_id"] != callerTrace {
+				t.Errorf("%s: %q logged with trace_id %v, want %s", path, entry["msg"], entry["trace_id"], callerTrace)
+			}
+		}
+	}
+}
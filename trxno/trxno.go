@@ -0,0 +1,160 @@
+// Package trxno parses, formats and generates receipt numbers (no_transaksi)
+// in the format TRX/BB/YYMMDD/NNNNN. Sequences restart per branch and day, so the branch is part
+// of the number. Imported receipts use the older TRX/YYMMDD/NNNNN, which is still accepted.
+package trxno
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Prefix is the fixed first part of every receipt number
+const Prefix = "TRX"
+
+// dateLayout is the YYMMDD part of a receipt number
+const dateLayout = "060102"
+
+// sequenceWidth is the minimum zero-padded width of the sequence part
+const sequenceWidth = 5
+
+// branchWidth is the minimum zero-padded width of the branch part
+const branchWidth = 2
+
+// ErrInvalidFormat is returned when a string is not a valid receipt number or sequence
+var ErrInvalidFormat = errors.New("invalid receipt number, expected TRX/BB/YYMMDD/NNNNN")
+
+// Number is a parsed receipt number
+type Number struct {
+	BranchID int       // 0 for numbers in the legacy format, which does not name the branch
+	Date     time.Time // Business date, at midnight UTC
+	Sequence int       // Per-branch, per-day counter starting at 1
+}
+
+// String formats the number as TRX/BB/YYMMDD/NNNNN, or TRX/YYMMDD/NNNNN without a branch
+func (n Number) String() string {
+	if n.BranchID > 0 {
+		return fmt.Sprintf("%s/%0*d/%s/%0*d", Prefix, branchWidth, n.BranchID,
+			n.Date.Format(dateLayout), sequenceWidth, n.Sequence)
+	}
+	return fmt.Sprintf("%s/%s/%0*d", Prefix, n.Date.Format(dateLayout), sequenceWidth, n.Sequence)
+}
+
+// Parse validates a full receipt number such as TRX/03/260116/01444 or, in the legacy format,
+// TRX/260116/01444
+func Parse(s string) (Number, error) {
+	parts := strings.Split(strings.TrimSpace(s), "/")
+	if len(parts) < 3 || len(parts) > 4 || !strings.EqualFold(parts[0], Prefix) {
+		return Number{}, ErrInvalidFormat
+	}
+
+	var branchID int
+	if len(parts) == 4 {
+		// The branch part has the same shape as a sequence: digits, not zero
+		id, err := ParseSequence(parts[1])
+		if err != nil {
+			return Number{}, ErrInvalidFormat
+		}
+		branchID = id
+		parts = append(parts[:1], parts[2:]...)
+	}
+
+	if len(parts[1]) != len(dateLayout) {
+		return Number{}, ErrInvalidFormat
+	}
+	date, err := time.Parse(dateLayout, parts[1])
+	if err != nil {
+		return Number{}, ErrInvalidFormat
+	}
+
+	seq, err := ParseSequence(parts[2])
+	if err != nil {
+		return Number{}, err
+	}
+
+	return Number{BranchID: branchID, Date: date, Sequence: seq}, nil
+}
+
+// ParseSequence validates the sequence part on its own, e.g. "01444" or "1444"
+func ParseSequence(s string) (int, error) {
+	if s == "" || len(s) > 9 {
+		return 0, ErrInvalidFormat
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return 0, ErrInvalidFormat
+		}
+	}
+
+	seq, err := strconv.Atoi(s)
+	if err != nil || seq < 1 {
+		return 0, ErrInvalidFormat
+	}
+	return seq, nil
+}
+
+// Next reserves the next receipt number for a branch on a business date.
+// It must run inside the transaction that inserts the row: the counter row stays locked until
+// commit, so concurrent cashiers queue up instead of reading the same value, and a rollback
+// releases the number. The first number of a day continues after any imported receipts.
+func Next(tx *gorm.DB, branchID int, businessDate time.Time) (Number, error) {
+	day := businessDate.Format("2006-01-02")
+	// Imported receipts of the day are in the legacy format
+	existing := fmt.Sprintf("%s/%s/%%", Prefix, businessDate.Format(dateLayout))
+
+	var seq int
+	err := tx.Raw(`
+		INSERT INTO transaction_sequences (branch_id, business_date, last_seq)
+		VALUES (?, ?, (
+			SELECT COALESCE(MAX(CAST(SPLIT_PART(no_transaksi, '/', 3) AS INTEGER)), 0) + 1
+			FROM transactions
+			WHERE branch_id = ?
+				AND no_transaksi LIKE ?
+				AND SPLIT_PART(no_transaksi, '/', 3) ~ '^[0-9]{1,9}$'
+		))
+		ON CONFLICT (branch_id, business_date)
+		DO UPDATE SET last_seq = transaction_sequences.last_seq + 1
+		RETURNING last_seq
+	`, branchID, day, branchID, existing).Scan(&seq).Error
+	if err != nil {
+		return Number{}, err
+	}
+
+	date, _ := time.Parse("2006-01-02", day)
+	return Number{BranchID: branchID, Date: date, Sequence: seq}, nil
+}
+
+// Where returns a condition on no_transaksi that matches n however its parts were zero-padded
+// when stored. A number without a branch matches the legacy format only.
+func (n Number) Where() (string, []any) {
+	if n.BranchID == 0 {
+		return WhereLegacy(n.Date, n.Sequence)
+	}
+	cond, args := whereBranch(n.Date, n.Sequence)
+	return "(" + cond + " AND LTRIM(SPLIT_PART(no_transaksi, '/', 2), '0') = ?)", append(args, strconv.Itoa(n.BranchID))
+}
+
+// WhereLegacy matches the legacy TRX/YYMMDD/NNNNN number of a date and sequence
+func WhereLegacy(date time.Time, seq int) (string, []any) {
+	return "(no_transaksi LIKE ? AND SPLIT_PART(no_transaksi, '/', 4) = '' AND LTRIM(SPLIT_PART(no_transaksi, '/', 3), '0') = ?)",
+		[]any{Prefix + "/" + date.Format(dateLayout) + "/%", strconv.Itoa(seq)}
+}
+
+// WhereAnyBranch matches the number of every branch for a date and sequence, and the legacy one
+func WhereAnyBranch(date time.Time, seq int) (string, []any) {
+	branchCond, branchArgs := whereBranch(date, seq)
+	legacyCond, legacyArgs := WhereLegacy(date, seq)
+	return "(" + branchCond + " OR " + legacyCond + ")", append(branchArgs, legacyArgs...)
+}
+
+// whereBranch matches TRX/BB/YYMMDD/NNNNN numbers of a date and sequence, in any branch
+func whereBranch(date time.Time, seq int) (string, []any) {
+	day := date.Format(dateLayout)
+	cond := "(no_transaksi LIKE ? AND SPLIT_PART(no_transaksi, '/', 3) = ? AND SPLIT_PART(no_transaksi, '/', 5) = ''" +
+		" AND LTRIM(SPLIT_PART(no_transaksi, '/', 4), '0') = ?)"
+	return cond, []any{Prefix + "/%/" + day + "/%", day, strconv.Itoa(seq)}
+}
@@ -0,0 +1,155 @@
+package trxno
+
+import (
+	"os"
+	"rekap-backend/migration"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestParse(t *testing.T) {
+	day := time.Date(2026, 1, 16, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		in   string
+		want Number
+		str  string
+	}{
+		{"TRX/03/260116/01444", Number{BranchID: 3, Date: day, Sequence: 1444}, "TRX/03/260116/01444"},
+		{"TRX/3/260116/1444", Number{BranchID: 3, Date: day, Sequence: 1444}, "TRX/03/260116/01444"},
+		{"TRX/123/260116/00001", Number{BranchID: 123, Date: day, Sequence: 1}, "TRX/123/260116/00001"},
+		{"trx/01/260116/123456", Number{BranchID: 1, Date: day, Sequence: 123456}, "TRX/01/260116/123456"},
+		{" TRX/07/260116/00042 ", Number{BranchID: 7, Date: day, Sequence: 42}, "TRX/07/260116/00042"},
+
+		// Legacy numbers of imported receipts have no branch part
+		{"TRX/260116/01444", Number{Date: day, Sequence: 1444}, "TRX/260116/01444"},
+		{"TRX/260116/1444", Number{Date: day, Sequence: 1444}, "TRX/260116/01444"},
+		{"TRX/260116/000001", Number{Date: day, Sequence: 1}, "TRX/260116/00001"},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.in)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.in, err)
+			continue
+		}
+		if got.BranchID != tt.want.BranchID || !got.Date.Equal(tt.want.Date) || got.Sequence != tt.want.Sequence {
+			t.Errorf("Parse(%q) = %+v, want %+v", tt.in, got, tt.want)
+		}
+		if s := got.String(); s != tt.str {
+			t.Errorf("Parse(%q).String() = %q, want %q", tt.in, s, tt.str)
+		}
+
+		// The canonical form parses back to the same number
+		again, err := Parse(got.String())
+		if err != nil || again != got {
+			t.Errorf("Parse(%q) = %+v, %v, want %+v", got.String(), again, err, got)
+		}
+	}
+}
+
+func TestParseRejects(t *testing.T) {
+	for _, in := range []string{
+		"",
+		"TRX",
+		"TRX/260116",
+		"INV/260116/01444",
+		"TRX/26011/01444",  // Short date
+		"TRX/261316/01444", // Month 13
+		"TRX/260116/",      // No sequence
+		"TRX/260116/0",     // Sequences start at 1
+		"TRX/260116/-5",
+		"TRX/260116/1e3",
+		"TRX/260116/1234567890", // Too long
+		"TRX/0/260116/01444",    // Branches start at 1
+		"TRX/a/260116/01444",
+		"TRX//260116/01444",
+		"TRX/1/2/260116/01444",
+		"TRX/03/260116/01444/",
+	} {
+		if n, err := Parse(in); err == nil {
+			t.Errorf("Parse(%q) = %+v, want an error", in, n)
+		}
+	}
+}
+
+func TestParseSequence(t *testing.T) {
+	tests := map[string]int{"1": 1, "01444": 1444, "000000001": 1, "999999999": 999999999}
+	for in, want := range tests {
+		if got, err := ParseSequence(in); err != nil || got != want {
+			t.Errorf("ParseSequence(%q) = %d, %v, want %d", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"", "0", "000", "12a", " 12", "+12", "1000000000"} {
+		if got, err := ParseSequence(in); err == nil {
+			t.Errorf("ParseSequence(%q) = %d, want an error", in, got)
+		}
+	}
+}
+
+// TestNextConcurrent reserves numbers from many goroutines at once and expects every one of them
+// exactly once. It needs a Postgres database: set TEST_DATABASE_DSN to run it.
+func TestNextConcurrent(t *testing.T) {
+	dsn := os.Getenv("TEST_DATABASE_DSN")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_DSN is not set")
+	}
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := migration.Run(db); err != nil {
+		t.Fatal(err)
+	}
+
+	// A branch and day no real data uses
+	const branchID = 987654
+	day := time.Date(2001, 2, 3, 0, 0, 0, 0, time.UTC)
+	cleanup := func() {
+		db.Exec("DELETE FROM transaction_sequences WHERE branch_id = ?", branchID)
+	}
+	cleanup()
+	t.Cleanup(cleanup)
+
+	const workers = 25
+	var (
+		mu   sync.Mutex
+		seqs []int
+		wg   sync.WaitGroup
+	)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := db.Transaction(func(tx *gorm.DB) error {
+				n, err := Next(tx, branchID, day)
+				if err != nil {
+					return err
+				}
+				if n.BranchID != branchID || !n.Date.Equal(day) {
+					t.Errorf("Next = %+v, want branch %d on %s", n, branchID, day)
+				}
+				mu.Lock()
+				seqs = append(seqs, n.Sequence)
+				mu.Unlock()
+				return nil
+			})
+			if err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	sort.Ints(seqs)
+	for i, seq := range seqs {
+		if seq != i+1 {
+			t.Fatalf("sequences %v, want 1..%d without gaps or duplicates", seqs, workers)
+		}
+	}
+}